@@ -4,7 +4,10 @@ import (
 	"270725/internal/config"
 	v1 "270725/internal/rest/v1"
 	"270725/internal/services"
+	"270725/internal/storage/boltdb"
 	"270725/internal/storage/inmemory"
+	"270725/internal/storage/localfs"
+	"270725/internal/storage/s3"
 	"context"
 	"errors"
 	"fmt"
@@ -25,7 +28,33 @@ func main() {
 	rootCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	server := newServer(cfg, logger)
+	repo, queue, closeRepo, err := newRepository(cfg)
+	if err != nil {
+		panic(fmt.Errorf("failed to create repository: %w", err))
+	}
+	defer closeRepo()
+	logger.Info("starting repository", slog.String("type", string(cfg.StorageType)))
+
+	requester, err := services.NewRequesterService(cfg)
+	if err != nil {
+		panic(fmt.Errorf("failed to create requester: %w", err))
+	}
+
+	results, err := newResultStore(rootCtx, cfg)
+	if err != nil {
+		panic(fmt.Errorf("failed to create result store: %w", err))
+	}
+	logger.Info("starting result store", slog.String("type", string(cfg.ResultStorageType)))
+
+	taskService := newTaskService(cfg, logger, repo, queue, requester, results)
+	if err := taskService.Start(rootCtx); err != nil {
+		panic(fmt.Errorf("failed to start task service: %w", err))
+	}
+	logger.Info("starting task service")
+
+	go services.NewJanitor(cfg, logger, taskService).Run(rootCtx)
+
+	server := newServer(cfg, logger, taskService)
 	go run(logger, server)
 
 	logger.Info("starting server", slog.String("addr", server.Addr))
@@ -35,12 +64,23 @@ func main() {
 
 	<-stop
 
-	ctx, cancel := context.WithTimeout(rootCtx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(rootCtx, cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("failed to gracefully shutdown the server", slog.String("error", err.Error()))
 	}
+	if err := taskService.Shutdown(ctx); err != nil {
+		logger.Error("failed to gracefully shutdown the task service", slog.String("error", err.Error()))
+	}
+
+	// Downloads are finished or aborted once the task service is stopped, so the requester does not wait long.
+	requesterCtx, cancelRequester := context.WithTimeout(rootCtx, time.Second)
+	defer cancelRequester()
+
+	if err := requester.Shutdown(requesterCtx); err != nil {
+		logger.Error("failed to gracefully shutdown the requester", slog.String("error", err.Error()))
+	}
 }
 
 func run(logger *slog.Logger, server *http.Server) {
@@ -49,19 +89,47 @@ func run(logger *slog.Logger, server *http.Server) {
 	}
 }
 
-func newServer(cfg config.Config, logger *slog.Logger) *http.Server {
-	repo := inmemory.NewMemory()
-	logger.Info("starting repository")
-
-	requester := services.NewRequesterService(int(cfg.TasksBufferSize * cfg.LinksInTask))
-	archiver, err := services.NewZipper(cfg.ArchivesDir)
-	if err != nil {
-		panic(fmt.Errorf("failed to create archiver: %w", err))
+func newRepository(cfg config.Config) (services.TaskRepository, services.TaskQueue, func(), error) {
+	switch cfg.StorageType {
+	case config.StorageTypeBolt:
+		repo, err := boltdb.NewBolt(cfg.StoragePath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		queue, err := boltdb.NewQueue(repo)
+		if err != nil {
+			_ = repo.Close()
+			return nil, nil, nil, err
+		}
+
+		return repo, queue, func() { _ = repo.Close() }, nil
+	default:
+		return inmemory.NewMemory(), inmemory.NewQueue(), func() {}, nil
 	}
+}
 
-	taskService := services.NewTaskService(cfg, logger, repo, requester, archiver)
-	logger.Info("starting task service")
+func newTaskService(
+	cfg config.Config,
+	logger *slog.Logger,
+	repo services.TaskRepository,
+	queue services.TaskQueue,
+	requester services.RequesterClient,
+	results services.ResultStore,
+) *services.TaskService {
+	return services.NewTaskService(cfg, logger, repo, queue, requester, services.NewArchivers(cfg), results)
+}
+
+func newResultStore(ctx context.Context, cfg config.Config) (services.ResultStore, error) {
+	switch cfg.ResultStorageType {
+	case config.ResultStorageTypeS3:
+		return s3.NewS3(ctx, cfg.S3Config)
+	default:
+		return localfs.NewLocal(cfg.ArchivesDir)
+	}
+}
 
+func newServer(cfg config.Config, logger *slog.Logger, taskService *services.TaskService) *http.Server {
 	handler := v1.NewHandler(logger, taskService)
 
 	e := echo.New()
@@ -16,9 +16,24 @@ const (
 	LogLevelWarn  LogLevel = "warn"
 )
 
+type StorageType string
+
+const (
+	StorageTypeMemory StorageType = "memory"
+	StorageTypeBolt   StorageType = "bolt"
+)
+
+type ResultStorageType string
+
+const (
+	ResultStorageTypeLocal ResultStorageType = "local"
+	ResultStorageTypeS3    ResultStorageType = "s3"
+)
+
 type Config struct {
 	LogLevel LogLevel `env:"LOG_LEVEL" env-default:"info" validate:"oneof=debug info warn error"`
 	ServerConfig
+	StorageConfig
 	TaskConfig
 	Filter
 }
@@ -29,16 +44,89 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `env:"READ_TIMEOUT" env-default:"5s"`
 	WriteTimeout time.Duration `env:"WRITE_TIMEOUT" env-default:"5s"`
 	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT" env-default:"10s"`
+	// ShutdownTimeout bounds waiting for running tasks on shutdown, unfinished tasks are resumed on the next start.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+}
+
+type StorageConfig struct {
+	StorageType StorageType `env:"STORAGE_TYPE" env-default:"memory" validate:"oneof=memory bolt"`
+	StoragePath string      `env:"STORAGE_PATH" env-default:"./data/tasks.db"`
+
+	// ResultStorageType selects where archives are kept, local archives are stored in ArchivesDir.
+	ResultStorageType ResultStorageType `env:"RESULT_STORAGE_TYPE" env-default:"local" validate:"oneof=local s3"`
+	// ResultRedirect makes the result endpoint redirect to a presigned link instead of streaming the archive,
+	// when the result storage supports it.
+	ResultRedirect   bool          `env:"RESULT_REDIRECT" env-default:"false"`
+	ResultPresignTTL time.Duration `env:"RESULT_PRESIGN_TTL" env-default:"15m"`
+	S3Config
+}
+
+type S3Config struct {
+	S3Endpoint  string `env:"S3_ENDPOINT"`
+	S3Bucket    string `env:"S3_BUCKET" env-default:"archives"`
+	S3AccessKey string `env:"S3_ACCESS_KEY"`
+	S3SecretKey string `env:"S3_SECRET_KEY"`
+	S3Region    string `env:"S3_REGION" env-default:"us-east-1"`
+	S3UseSSL    bool   `env:"S3_USE_SSL" env-default:"true"`
 }
 
 type TaskConfig struct {
 	TasksBufferSize uint   `env:"TASKS_BUFFER_SIZE" env-default:"3"`
+	MaxCreatedTasks uint   `env:"MAX_CREATED_TASKS" env-default:"10"`
+	MaxQueuedTasks  uint   `env:"MAX_QUEUED_TASKS" env-default:"100"`
 	LinksInTask     uint   `env:"LINKS_IN_TASK" env-default:"3"`
 	ArchivesDir     string `env:"ARCHIVES_DIR" env-default:"./archives"`
+	AutoStartTask   bool   `env:"AUTO_START_TASK" env-default:"true"`
+	// ArchiveFormat is used for tasks created without a format.
+	ArchiveFormat string `env:"ARCHIVE_FORMAT" env-default:"zip" validate:"oneof=zip tar tar.gz tar.zst"`
+	// ZipCompressionLevel is the deflate level of zip archives, 0 stores all files uncompressed.
+	ZipCompressionLevel int `env:"ZIP_COMPRESSION_LEVEL" env-default:"6" validate:"min=0,max=9"`
+	// ZipStoredExtensions are already compressed file types that are stored in zip archives without deflate.
+	ZipStoredExtensions []string `env:"ZIP_STORED_EXTENSIONS" env-default:"jpg,jpeg,png,gif,webp,pdf,zip,gz,zst,mp3,mp4"`
+
+	CreatedTaskTimeout time.Duration `env:"CREATED_TASK_TIMEOUT" env-default:"10m"`
+	ArchiveTTL         time.Duration `env:"ARCHIVE_TTL" env-default:"24h"`
+	ArchivesQuota      int64         `env:"ARCHIVES_QUOTA" env-default:"0"`
+	JanitorInterval    time.Duration `env:"JANITOR_INTERVAL" env-default:"1m" validate:"gt=0"`
+
+	DownloadConnectTimeout time.Duration `env:"DOWNLOAD_CONNECT_TIMEOUT" env-default:"10s"`
+	DownloadHeaderTimeout  time.Duration `env:"DOWNLOAD_HEADER_TIMEOUT" env-default:"30s"`
+	DownloadTimeout        time.Duration `env:"DOWNLOAD_TIMEOUT" env-default:"10m"`
+
+	// MaxFileSize and MaxTaskSize limit bytes downloaded for a single link and for all links of a task, 0 disables a limit.
+	MaxFileSize int64 `env:"MAX_FILE_SIZE" env-default:"1073741824" validate:"min=0"`
+	MaxTaskSize int64 `env:"MAX_TASK_SIZE" env-default:"4294967296" validate:"min=0"`
+
+	DownloadAttempts        uint          `env:"DOWNLOAD_ATTEMPTS" env-default:"3" validate:"min=1"`
+	DownloadRetryBackoff    time.Duration `env:"DOWNLOAD_RETRY_BACKOFF" env-default:"500ms"`
+	DownloadRetryMaxBackoff time.Duration `env:"DOWNLOAD_RETRY_MAX_BACKOFF" env-default:"10s"`
+	DownloadRetryJitter     float64       `env:"DOWNLOAD_RETRY_JITTER" env-default:"0.2" validate:"min=0,max=1"`
+
+	RedirectMaxHops        uint `env:"REDIRECT_MAX_HOPS" env-default:"10"`
+	RedirectSameHost       bool `env:"REDIRECT_SAME_HOST" env-default:"false"`
+	RedirectAllowDowngrade bool `env:"REDIRECT_ALLOW_DOWNGRADE" env-default:"false"`
+
+	// HostMaxConnections and HostRateLimit (requests per second) limit downloads from a single host, 0 disables a limit.
+	HostMaxConnections uint    `env:"HOST_MAX_CONNECTIONS" env-default:"4"`
+	HostRateLimit      float64 `env:"HOST_RATE_LIMIT" env-default:"0" validate:"min=0"`
+	HostRateBurst      uint    `env:"HOST_RATE_BURST" env-default:"1" validate:"min=1"`
+	// HostLimits override the limits for host globs, given as host=connections:rate:burst.
+	HostLimits []string `env:"HOST_LIMITS"`
 }
 
 type Filter struct {
 	AllowedExtensions []string `env:"ALLOWED_EXTENSIONS" env-default:"jpg,png,pdf"`
+
+	// BlockedNetworks are blocked for downloads in addition to loopback, link-local and private networks.
+	BlockedNetworks []string `env:"BLOCKED_NETWORKS" env-default:"0.0.0.0/8,100.64.0.0/10,192.0.0.0/24,198.18.0.0/15"`
+	// AllowedNetworks are allowed for downloads even if they are blocked.
+	AllowedNetworks []string `env:"ALLOWED_NETWORKS"`
+
+	// AllowedSchemes are link schemes links may use, also after redirects.
+	AllowedSchemes []string `env:"ALLOWED_SCHEMES" env-default:"http,https"`
+	// AllowedHosts and DeniedHosts are host globs like *.example.com, an empty AllowedHosts allows all hosts.
+	AllowedHosts []string `env:"ALLOWED_HOSTS"`
+	DeniedHosts  []string `env:"DENIED_HOSTS"`
 }
 
 func MustLoad() Config {
@@ -1,5 +1,18 @@
 package models
 
+import "time"
+
+type TaskStatus string
+
+const (
+	CreatedTaskStatus    TaskStatus = "created"
+	QueuedTaskStatus     TaskStatus = "queued"
+	ProcessingTaskStatus TaskStatus = "processing"
+	ArchivedTaskStatus   TaskStatus = "archived"
+	FailedTaskStatus     TaskStatus = "failed"
+	CancelledTaskStatus  TaskStatus = "cancelled"
+)
+
 type TaskLinkStatus string
 
 const (
@@ -9,12 +22,101 @@ const (
 	ErrorTaskLinkStatus     TaskLinkStatus = "error"
 )
 
+type LinkFailureReason string
+
+const (
+	HTTPStatusFailureReason     LinkFailureReason = "http_status"
+	DNSFailureReason            LinkFailureReason = "dns"
+	ConnectFailureReason        LinkFailureReason = "connect"
+	TimeoutFailureReason        LinkFailureReason = "timeout"
+	SizeLimitFailureReason      LinkFailureReason = "size_limit"
+	DisallowedTypeFailureReason LinkFailureReason = "disallowed_type"
+	BlockedAddressFailureReason LinkFailureReason = "blocked_address"
+	DisallowedLinkFailureReason LinkFailureReason = "disallowed_link"
+	RedirectFailureReason       LinkFailureReason = "redirect"
+	NetworkFailureReason        LinkFailureReason = "network"
+	CancelledFailureReason      LinkFailureReason = "cancelled"
+	InternalFailureReason       LinkFailureReason = "internal"
+)
+
+type ArchiveFormat string
+
+const (
+	ZipArchiveFormat    ArchiveFormat = "zip"
+	TarArchiveFormat    ArchiveFormat = "tar"
+	TarGzArchiveFormat  ArchiveFormat = "tar.gz"
+	TarZstArchiveFormat ArchiveFormat = "tar.zst"
+)
+
 type Task struct {
-	ID        string
-	FilesLink []*FileLink
+	ID         string
+	Status     TaskStatus
+	FilesLink  []*FileLink
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExpiresAt  time.Time
+	// ArchiveFormat is the format the archive is written in.
+	ArchiveFormat ArchiveFormat
+	// ArchiveSize and ArchiveSHA256 describe the archive written for an archived task.
+	ArchiveSize   int64
+	ArchiveSHA256 string
+	// QueuePosition is 1-based position of a queued task, it is filled on read and never stored.
+	QueuePosition int `json:"-"`
 }
 
 type FileLink struct {
-	Link   string `validate:"url"`
-	Status TaskLinkStatus
+	Link          string `validate:"url"`
+	Status        TaskLinkStatus
+	FailureReason LinkFailureReason
+	Error         string
+	HTTPStatus    int
+	BytesReceived int64
+	ContentType   string
+	Attempts      uint
+
+	// SizeLimit is the byte limit the file exceeded when it failed with the size_limit reason.
+	SizeLimit int64
+	// Name is the file name in the archive requested with the link.
+	Name string
+	// ArchiveName is the name the downloaded file got in the archive.
+	ArchiveName string
+	// FinalURL is the URL the file was downloaded from after redirects.
+	FinalURL string
+	// Redirects are the URLs the link was redirected to in order.
+	Redirects []string
+}
+
+// SetStatus changes task status and stamps the matching lifecycle timestamp.
+func (t *Task) SetStatus(status TaskStatus, at time.Time) {
+	t.Status = status
+
+	switch status {
+	case ProcessingTaskStatus:
+		t.StartedAt = at
+	case ArchivedTaskStatus, FailedTaskStatus, CancelledTaskStatus:
+		t.FinishedAt = at
+	}
+}
+
+// IsFinished reports whether the task reached a terminal status.
+func (t *Task) IsFinished() bool {
+	switch t.Status {
+	case ArchivedTaskStatus, FailedTaskStatus, CancelledTaskStatus:
+		return true
+	}
+
+	return false
+}
+
+// Clone returns a deep copy of the task.
+func (t *Task) Clone() *Task {
+	task := *t
+	task.FilesLink = make([]*FileLink, 0, len(t.FilesLink))
+	for _, link := range t.FilesLink {
+		fileLink := *link
+		task.FilesLink = append(task.FilesLink, &fileLink)
+	}
+
+	return &task
 }
@@ -22,15 +22,27 @@ type ServerInterface interface {
 	// create new task
 	// (POST /task)
 	AddTask(ctx echo.Context) error
+	// delete task and its archive
+	// (DELETE /task/{id})
+	DeleteTask(ctx echo.Context, id string) error
 	// get task
 	// (GET /task/{id})
 	GetTask(ctx echo.Context, id string) error
+	// cancel task processing
+	// (POST /task/{id}/cancel)
+	CancelTask(ctx echo.Context, id string) error
 	// add link to task
 	// (POST /task/{id}/link)
 	AddLink(ctx echo.Context, id string) error
 	// task result archive
 	// (GET /task/{id}/result)
-	GetResult(ctx echo.Context, id string) error
+	GetResult(ctx echo.Context, id string, params GetResultParams) error
+	// start task processing with already added links
+	// (POST /task/{id}/start)
+	StartTask(ctx echo.Context, id string) error
+	// current usage of task slots
+	// (GET /usage)
+	GetUsage(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -65,6 +77,22 @@ func (w *ServerInterfaceWrapper) AddTask(ctx echo.Context) error {
 	return err
 }
 
+// DeleteTask converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteTask(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: false})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteTask(ctx, id)
+	return err
+}
+
 // GetTask converts echo context to params.
 func (w *ServerInterfaceWrapper) GetTask(ctx echo.Context) error {
 	var err error
@@ -81,6 +109,22 @@ func (w *ServerInterfaceWrapper) GetTask(ctx echo.Context) error {
 	return err
 }
 
+// CancelTask converts echo context to params.
+func (w *ServerInterfaceWrapper) CancelTask(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: false})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CancelTask(ctx, id)
+	return err
+}
+
 // AddLink converts echo context to params.
 func (w *ServerInterfaceWrapper) AddLink(ctx echo.Context) error {
 	var err error
@@ -108,8 +152,42 @@ func (w *ServerInterfaceWrapper) GetResult(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetResultParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetResult(ctx, id, params)
+	return err
+}
+
+// StartTask converts echo context to params.
+func (w *ServerInterfaceWrapper) StartTask(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: false})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.StartTask(ctx, id)
+	return err
+}
+
+// GetUsage converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUsage(ctx echo.Context) error {
+	var err error
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetResult(ctx, id)
+	err = w.Handler.GetUsage(ctx)
 	return err
 }
 
@@ -144,8 +222,12 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	router.GET(baseURL+"/swagger", wrapper.GetAPI)
 	router.GET(baseURL+"/task", wrapper.GetAllTasks)
 	router.POST(baseURL+"/task", wrapper.AddTask)
+	router.DELETE(baseURL+"/task/:id", wrapper.DeleteTask)
 	router.GET(baseURL+"/task/:id", wrapper.GetTask)
+	router.POST(baseURL+"/task/:id/cancel", wrapper.CancelTask)
 	router.POST(baseURL+"/task/:id/link", wrapper.AddLink)
 	router.GET(baseURL+"/task/:id/result", wrapper.GetResult)
+	router.POST(baseURL+"/task/:id/start", wrapper.StartTask)
+	router.GET(baseURL+"/usage", wrapper.GetUsage)
 
 }
@@ -18,20 +18,42 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+xXTY/jNgz9KwLboz1O2u3FtynQLgbYQ1H0VgQLrcU42siSVqIzmw383wvRzuTDnu00",
-	"SQdt0ZMDSqLIx/dIZQeVa7yzaClCuYNYrbCR/PP+l4f08cF5DKSRjdLr9KGtRyghUtC2hgw+57XLkzGP",
-	"a+1z50k7K03unbaEAUoKLXZdtj/oPnzEiqDL4KcQXBjfozBWQbOby+/LAJP395VTeOQlbakxXBX2z9rg",
-	"O23XD3bpxtEbbdfXhB1JUsue0LYNlL+DxUfIQNv3PrgKY4SMC2eQUMGQJyyyGxbmNxnX48yW2mB8N6Sn",
-	"CRu2fhtwCSV8UxzIVAxMKk6QOlwkQ5DbvwCJVhcDmra5JgXraQvlUpo4lXIy6aGcpMmktS/ae2bKBkNk",
-	"LsL8bnY3S4k4j5blAN/fze7mkIGXtGI8ivgo60Sxcgc10ojQyZjkxU6CTMYHBSW83ZsDRu9s7DH/bjZL",
-	"n8pZQku9Cr3RFZ8rPsZeIj3ef1aN5J4zPY2Hl1FoK9hdl8Gb2ZubXdprfOJa60gsXWuVwGFLBrFtGhm2",
-	"UEJAaoONHJKQXovjwxmQrGOSxh7rRTpd0EDbZ2E3JjE7TmJ/WLuqAC/SBevrXA8TGKWEojA60hk8NZKQ",
-	"xggaQt7jwQgsugy8ixMY3CvFV5/nf7Cf5T6/GQ/6lMcp3iuFivMQSYChYecJnB9uSP1nWcitwkojIoYN",
-	"hkkuVgElobD4yHGO4d5zr9hp1X2NgJPgv32yexlkg4QhOd9NcEFoxZMASu43kIGVDY+1ZD9AcXmrlF7n",
-	"aWTWaHP8TEHmfa472EijlSRkcX5qdUCVAF38jQ3rOc7QIJ9X6VOM+1Oz+kcRM3WBFzCy2D9JppuCVIqH",
-	"+kRTGOz/Wl5+ajHSj05tL2vht3zYTT2zztt/d2X/vf5NNkHElLaQqUv3knsF7n+QSgzF+1/mLFDBVSA3",
-	"KfeMaxRHsg8YW0Nfm0e/9jsmJtLTyn9/Jn3R/rSQ/Ssk8VDbVIHzf1ZT7FmhkKFa6Q2KpcHXZW1f52Py",
-	"ntDneM8Q45hBA1cWfEfPxb7gbTBQQiG9LjbzArpF90cAAAD//9nRDuU3EAAA",
+	"H4sIAAAAAAAC/+xaS28bORL+KwR3jy1LcZwsRsAePM4kY8AIvLZzmVnDoJolieNuskOyJUuG/vuiyH6q",
+	"qYxe8WYmuSQKyWYVi1999WCeaazSTEmQ1tDhMzXxFFLmfp5fX+JfmVYZaCvADbJM4F92kQEdUmO1kBMa",
+	"0afeRPVwsGceRdZTmRVKsqSXKSEtaDq0OofVKio/VKM/ILZ0FdFzHU/FDN4rnTKLW4PMUzr8nS5FRiNq",
+	"mfZ/nkyWxY+lsfQ+CqiAX/ZmTEuWorK/t/f+ze3XGrpjOjD0YRkY/A2FriL6i9ZKd83CwcRauFPvb56I",
+	"Au7+ECsOjV1wyQT0QVZ+LxK4EvLxUo5V4FL9YT+yFDqHoWORAEGTEiGJnQIpVkfEgCVKxuBG3TJhyllO",
+	"o72twKyFNLNdw1KZpyPQRI0JV3OZKMZJuZikjEMtdFejRXS0sGBuIAan/fCZjgtI4l5vzw7ZOlbSgrR3",
+	"7vv1M2kwmZIGyIVf1cNleES0aiLkI40Ow1PgSplIcg2Eg2UiMQcIKHa6AWaU7AqaTxctcKTCGCEnZKxV",
+	"2sQSjSq3n1qbPRjLbI56cYl/xkpKBHJErUhB5fjLiCU8JCIV+A8uDEsSNQf+4E4S0VGi4kfgD4xzDca0",
+	"1xRW1cCF9vtKsHOlcTBmMoYkcQB2Z5Us2ZJu3jeN8evd3fVteYzWzLuPt+tDF9UBW8N31Wlbw7diCVfF",
+	"yds7V0e881Zozf7sTXJeWWTDt1feOq3Zm9pUrfGPld3a52kYsTVx2bDo1hgTkiWfdNKF16ebqxpec2Yq",
+	"VgDuIcbGFjQp7/kQoCMsi+vc7MEetgT5O+zAu1OH+/6AiCJ34nSi4XMOxgInc2Gnx6Cg2vih6zOVCHd/",
+	"5WLgxCrUTWkOOvKLmMFw43hEwzg3qOQUZL0DklFT83I3oj09RVRYSE3XnHXEZFqzxQ6nM5Urdk6H8YQ4",
+	"gqoxCk8xAAfuY2elaM1lpKTmSuXjRSFTwbfkWglzx3IPmVax5wTMBhOwznN99LiPjpjtfYT5HTOP3RRk",
+	"XOV//9QwpkP6j36dmPaLrLTfThaDEsLbF/i+nbLTN2+7dzWFJwIS/ZaT21/Pe6dv3pJ4CvGjydPSk+tQ",
+	"tXdeUyghlgGXRAysiUIHcFnJUWEQa2AW+Lltmp1yZqGH0fWA88FTJjSY84Az4M4FG8+nIvawt8w8EiY5",
+	"EbbKGwlzeUkJwS30cxEiAXNVMGXl418CUisf3t/9x0IKM93BmKtoP6RHVPC9wwAuU6lwefKCDscsMaj8",
+	"5xxyuFZGlFVL+8pe9UYMOTYrVpTgdJ9xf3lF9CjoAzM7N9uFp6cfbXczVZexCvDSQnv8UQunlYs56Lho",
+	"0MrotkvjkEF8qL+opNVj/ynl1kPXTQ3q4fNal3rwfalVQ0pTv/2p9ZNhEwgwX2zFDFBaIAIXd4k+2LhD",
+	"6xbvSzEb0Fbc3AZFnEgyZ8K68kBpF9ENUZo43Bxbm5Q9nbcNc+ztL9bOe8T9u7ePQ6Io7a2wCc4tRZY5",
+	"QTPQpnDpk8HJALGiMpCuk0NfnwxOXqEXMTt1evbNnE1Qw+EznUCAyidgsTPkNtEMBy85HdIP5XCZDbvd",
+	"TgcD/KsogKlrIGWJiN13/T+KotGT3p9S4vWlP+la4MRpFyvddquIng3OjibU93sCYqWyZKxyyQkUSyJq",
+	"8jRleuFKAptraZxKhGWCND+OqGUTRzWlre/x674tUpeNZk+Su8I1u7av5w66gK2iJ0rqRM2AjbxXJ8LY",
+	"NfNMwBKWJDXTFPbAf7s2W6ZMwAbn3PlT5/z1eFG//Kz44mgQKHPWlTvhmnVfHU1MKSPqHroOtz5uihLn",
+	"g6+P81yaPMsUhu4qRxtXWcnZ6U9fXwUDeiZiV/iNcrNAuW9e4uhl+4egAqCDju7DGpEwd1fUxXLp2P1n",
+	"wVce0pjcdsHtx4P4ftecyphmKVjQKCUUR4nwvSs6dKxOyyYAdeO1TfbPJVkmelgsTUD24Mlq1vOHfqYz",
+	"lghM6RwFfs6FBo6Wve/4zVk4B6hS/5eicSe04vJvClreFMEqKUSZm6JGEFEfwP6t4DT46jRsi5j3vaMS",
+	"Q/cWTNf3VZerR4Kx3M8HwXnRnPqBz+3wWVW55P+M1LPBTy8kliUaGF+QsgvzbSUG7j48e7daFF/0mrLd",
+	"H/YZxsvHmU7+W4z/Zb1lv7S9qlbaPY+XfTSJyGhBOIxZnvhWv19jiGWPIOu3zuqZSOnDH1ZCHaD1cuzQ",
+	"auXwTmrAd9xLDcOa5sVKmBHj5cPWjwjuWMS/l1kVjOT+0dF0uEmDyRP7pQbFjV8RSDarmb8iQUWd3EXJ",
+	"GWjbequxyj/zudI4qt83/ADSgXuxrKmiPOXnHPSiPqZfT6MtkbD+IrZb6jHB/2TVQl3VmB8JyZxea135",
+	"VdTa4alnmT5si4N1WBrLd92h67CNuxwnQCM6BcYdTp/pOzGBUEDGF5K3Z9s+HZZxw0wZLvv3f/PB4HU8",
+	"Y0kO7qdHzxdQjlr/cscmoUa+wgbNbu+Ym8WgoNeDf3XlVE/qVhFGsC2tNNOLmk+6p/W0QYxVmk3W7Hql",
+	"/BV2BWUajJhI4KGt/1T3b6Ez9mJRpjBwI9i06L65ZkP7wHEGUtI65fu3l435qJsOlnC3jZm/RwV3+iId",
+	"BmLyUSosQgofwRqFw0uh2mkxZYZI5d/gvqtSsnik/tHaLr17vYD1/2mpNJfL4wuYBCvbvHyU3pQy+lfr",
+	"QMZYTny1RooXsNEPE2UxZfNrWoV9rjVI6+dcUK3WN2zgv7x323sbe/bLdUKHtM8y0Z+96tPV/ep/AwA2",
+	"H8LVgi8AAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -3,6 +3,34 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
 package boilerplate
 
+import (
+	"time"
+)
+
+// Defines values for ArchiveFormat.
+const (
+	ArchiveFormatTar    ArchiveFormat = "tar"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+	ArchiveFormatZip    ArchiveFormat = "zip"
+)
+
+// Defines values for FileLinkInfoFailureReason.
+const (
+	FailureReasonBlockedAddress FileLinkInfoFailureReason = "blocked_address"
+	FailureReasonCancelled      FileLinkInfoFailureReason = "cancelled"
+	FailureReasonConnect        FileLinkInfoFailureReason = "connect"
+	FailureReasonDNS            FileLinkInfoFailureReason = "dns"
+	FailureReasonDisallowedLink FileLinkInfoFailureReason = "disallowed_link"
+	FailureReasonDisallowedType FileLinkInfoFailureReason = "disallowed_type"
+	FailureReasonHTTPStatus     FileLinkInfoFailureReason = "http_status"
+	FailureReasonInternal       FileLinkInfoFailureReason = "internal"
+	FailureReasonNetwork        FileLinkInfoFailureReason = "network"
+	FailureReasonRedirect       FileLinkInfoFailureReason = "redirect"
+	FailureReasonSizeLimit      FileLinkInfoFailureReason = "size_limit"
+	FailureReasonTimeout        FileLinkInfoFailureReason = "timeout"
+)
+
 // Defines values for FileLinkInfoStatus.
 const (
 	FileLinkInfoStatusCompleted FileLinkInfoStatus = "completed"
@@ -11,11 +39,24 @@ const (
 	FileLinkInfoStatusNew       FileLinkInfoStatus = "new"
 )
 
+// Defines values for TaskStatus.
+const (
+	TaskStatusArchived   TaskStatus = "archived"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+	TaskStatusCreated    TaskStatus = "created"
+	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusProcessing TaskStatus = "processing"
+	TaskStatusQueued     TaskStatus = "queued"
+)
+
 // API defines model for API.
 type API struct {
 	Api string `json:"api,omitempty"`
 }
 
+// ArchiveFormat defines model for ArchiveFormat.
+type ArchiveFormat string
+
 // Error defines model for Error.
 type Error struct {
 	Description string `json:"description,omitempty"`
@@ -24,23 +65,103 @@ type Error struct {
 
 // FileLinkInfo defines model for FileLinkInfo.
 type FileLinkInfo struct {
-	Link   string             `json:"link,omitempty"`
-	Status FileLinkInfoStatus `json:"status,omitempty"`
+	// ArchiveName file name in the archive, set once the file is archived
+	ArchiveName string `json:"archiveName,omitempty"`
+
+	// Attempts number of download attempts made
+	Attempts      int   `json:"attempts,omitempty"`
+	BytesReceived int64 `json:"bytesReceived,omitempty"`
+
+	// ContentType response Content-Type of the link
+	ContentType string `json:"contentType,omitempty"`
+
+	// Error failure details
+	Error string `json:"error,omitempty"`
+
+	// FailureReason why the file is missing from the archive
+	FailureReason FileLinkInfoFailureReason `json:"failureReason,omitempty"`
+
+	// FinalUrl URL the file was downloaded from after redirects
+	FinalUrl string `json:"finalUrl,omitempty"`
+
+	// HttpStatus response status code of the link
+	HttpStatus int    `json:"httpStatus,omitempty"`
+	Link       string `json:"link,omitempty"`
+
+	// Name file name in the archive requested with the link
+	Name string `json:"name,omitempty"`
+
+	// Redirects URLs the link was redirected to in order, the last one is refused when the link failed with the redirect reason
+	Redirects []string `json:"redirects,omitempty"`
+
+	// SizeLimit byte limit the file exceeded, set with the size_limit failure reason
+	SizeLimit int64              `json:"sizeLimit,omitempty"`
+	Status    FileLinkInfoStatus `json:"status,omitempty"`
 }
 
+// FileLinkInfoFailureReason why the file is missing from the archive
+type FileLinkInfoFailureReason string
+
 // FileLinkInfoStatus defines model for FileLinkInfo.Status.
 type FileLinkInfoStatus string
 
+// NewTask defines model for NewTask.
+type NewTask struct {
+	Format *ArchiveFormat `json:"format,omitempty"`
+}
+
 // Task defines model for Task.
 type Task struct {
-	FilesLink []FileLinkInfo `json:"filesLink,omitempty"`
-	Id        string         `json:"id"`
+	// ArchiveSha256 hex encoded SHA-256 checksum of the archive
+	ArchiveSha256 string `json:"archiveSha256,omitempty"`
+
+	// ArchiveSize size of the archive in bytes
+	ArchiveSize int64     `json:"archiveSize,omitempty"`
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+
+	// ExpiresAt time after which the task and its archive are deleted
+	ExpiresAt  *time.Time     `json:"expiresAt,omitempty"`
+	FilesLink  []FileLinkInfo `json:"filesLink,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+	Format     *ArchiveFormat `json:"format,omitempty"`
+	Id         string         `json:"id"`
+
+	// QueuePosition 1-based position of the queued task in the processing queue
+	QueuePosition *int       `json:"queuePosition,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	Status        TaskStatus `json:"status,omitempty"`
+}
+
+// TaskStatus defines model for Task.Status.
+type TaskStatus string
+
+// Usage defines model for Usage.
+type Usage struct {
+	// ActiveTasks queued and processing tasks
+	ActiveTasks int `json:"activeTasks"`
+
+	// CreatedTasks tasks waiting for links or start
+	CreatedTasks    int `json:"createdTasks"`
+	MaxActiveTasks  int `json:"maxActiveTasks"`
+	MaxCreatedTasks int `json:"maxCreatedTasks"`
 }
 
 // AddLinkJSONBody defines parameters for AddLink.
 type AddLinkJSONBody = []struct {
 	Link string `json:"link,omitempty"`
+
+	// Name file name in the archive, by default the name is taken from the response or the link
+	Name string `json:"name,omitempty"`
+}
+
+// GetResultParams defines parameters for GetResult.
+type GetResultParams struct {
+	// Format convert the archive to the format, the task format is used by default
+	Format *ArchiveFormat `form:"format,omitempty" json:"format,omitempty"`
 }
 
+// AddTaskJSONRequestBody defines body for AddTask for application/json ContentType.
+type AddTaskJSONRequestBody = NewTask
+
 // AddLinkJSONRequestBody defines body for AddLink for application/json ContentType.
 type AddLinkJSONRequestBody = AddLinkJSONBody
@@ -3,20 +3,29 @@ package v1
 import (
 	"270725/internal/models"
 	bp "270725/internal/rest/v1/boileplate"
+	"270725/internal/services"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"io"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 type TaskService interface {
-	NewTask(ctx context.Context) (string, error)
+	NewTask(ctx context.Context, format models.ArchiveFormat) (string, error)
 	GetAllTasks(ctx context.Context) ([]*models.Task, error)
 	GetTask(ctx context.Context, id string) (*models.Task, error)
 	AddLinksToTask(ctx context.Context, taskID string, links []*models.FileLink) (*models.Task, error)
-	GetTaskResult(ctx context.Context, taskID string) (string, string, error)
+	StartTask(ctx context.Context, taskID string) (*models.Task, error)
+	CancelTask(ctx context.Context, taskID string) (*models.Task, error)
+	DeleteTask(ctx context.Context, taskID string) error
+	GetTaskResult(ctx context.Context, taskID string, format models.ArchiveFormat) (*services.TaskResult, error)
+	Usage() services.AdmissionUsage
 }
 
 func RegisterHandler(router *echo.Echo, handler *Handler) {
@@ -36,15 +45,36 @@ func NewHandler(log *slog.Logger, taskService TaskService) *Handler {
 	}
 }
 
+func (h *Handler) GetUsage(c echo.Context) error {
+	usage := h.taskService.Usage()
+
+	return c.JSON(http.StatusOK, bp.Usage{
+		CreatedTasks:    usage.Created,
+		MaxCreatedTasks: usage.MaxCreated,
+		ActiveTasks:     usage.Active,
+		MaxActiveTasks:  usage.MaxActive,
+	})
+}
+
 func (h *Handler) AddTask(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	taskID, err := h.taskService.NewTask(ctx)
+	newTask := bp.AddTaskJSONRequestBody{}
+	if err := c.Bind(&newTask); err != nil {
+		return fmt.Errorf("failed to bind new task: %w", err)
+	}
+
+	taskID, err := h.taskService.NewTask(ctx, convertRequestFormat(newTask.Format))
 	if err != nil {
 		return fmt.Errorf("failed to create new task: %w", err)
 	}
 
-	return c.JSON(http.StatusCreated, bp.Task{Id: taskID})
+	task, err := h.taskService.GetTask(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to get new task: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, convertTask(task))
 }
 
 func (h *Handler) GetAllTasks(c echo.Context) error {
@@ -57,10 +87,7 @@ func (h *Handler) GetAllTasks(c echo.Context) error {
 
 	tasksResponse := make([]bp.Task, 0, len(tasks))
 	for _, task := range tasks {
-		tasksResponse = append(tasksResponse, bp.Task{
-			Id:        task.ID,
-			FilesLink: convertLinks(task.FilesLink),
-		})
+		tasksResponse = append(tasksResponse, convertTask(task))
 	}
 
 	return c.JSON(http.StatusOK, tasksResponse)
@@ -80,12 +107,7 @@ func (h *Handler) AddLink(c echo.Context, id string) error {
 		return fmt.Errorf("failed to add link: %w", err)
 	}
 
-	responseTask := bp.Task{
-		Id:        task.ID,
-		FilesLink: convertLinks(task.FilesLink),
-	}
-
-	return c.JSON(http.StatusCreated, responseTask)
+	return c.JSON(http.StatusCreated, convertTask(task))
 }
 
 func (h *Handler) GetTask(c echo.Context, id string) error {
@@ -96,31 +118,161 @@ func (h *Handler) GetTask(c echo.Context, id string) error {
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	responseTask := bp.Task{
-		Id:        task.ID,
-		FilesLink: convertLinks(task.FilesLink),
+	return c.JSON(http.StatusOK, convertTask(task))
+}
+
+func (h *Handler) DeleteTask(c echo.Context, id string) error {
+	ctx := c.Request().Context()
+
+	if err := h.taskService.DeleteTask(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	return c.JSON(http.StatusOK, responseTask)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (h *Handler) GetResult(c echo.Context, id string) error {
+func (h *Handler) StartTask(c echo.Context, id string) error {
 	ctx := c.Request().Context()
 
-	filePath, name, err := h.taskService.GetTaskResult(ctx, id)
+	task, err := h.taskService.StartTask(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to start task: %w", err)
+	}
+
+	return c.JSON(http.StatusAccepted, convertTask(task))
+}
+
+func (h *Handler) CancelTask(c echo.Context, id string) error {
+	ctx := c.Request().Context()
+
+	task, err := h.taskService.CancelTask(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel task: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, convertTask(task))
+}
+
+func (h *Handler) GetResult(c echo.Context, id string, params bp.GetResultParams) error {
+	ctx := c.Request().Context()
+
+	result, err := h.taskService.GetTaskResult(ctx, id, convertRequestFormat(params.Format))
 	if err != nil {
 		return fmt.Errorf("failed to get result: %w", err)
 	}
 
-	return c.Attachment(filePath, name)
+	if result.SHA256 != "" {
+		c.Response().Header().Set("ETag", `"`+result.SHA256+`"`)
+		if digest, err := hex.DecodeString(result.SHA256); err == nil {
+			c.Response().Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(digest))
+		}
+	}
+
+	if result.URL != "" {
+		return c.Redirect(http.StatusTemporaryRedirect, result.URL)
+	}
+	defer result.Content.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", result.Name))
+
+	// A converted archive is produced while it is sent, so its size is unknown and ranges are not supported.
+	content, ok := result.Content.(io.ReadSeeker)
+	if !ok {
+		return c.Stream(http.StatusOK, result.MIMEType, result.Content)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, result.MIMEType)
+	http.ServeContent(c.Response(), c.Request(), result.Name, result.ModTime, content)
+
+	return nil
+}
+
+func convertTask(task *models.Task) bp.Task {
+	return bp.Task{
+		Id:            task.ID,
+		Status:        convertTaskStatus(task.Status),
+		CreatedAt:     task.CreatedAt,
+		StartedAt:     convertTime(task.StartedAt),
+		FinishedAt:    convertTime(task.FinishedAt),
+		ExpiresAt:     convertTime(task.ExpiresAt),
+		QueuePosition: convertQueuePosition(task.QueuePosition),
+		Format:        convertArchiveFormat(task.ArchiveFormat),
+		ArchiveSize:   task.ArchiveSize,
+		ArchiveSha256: task.ArchiveSHA256,
+		FilesLink:     convertLinks(task.FilesLink),
+	}
+}
+
+func convertTaskStatus(status models.TaskStatus) bp.TaskStatus {
+	switch status {
+	case models.CreatedTaskStatus:
+		return bp.TaskStatusCreated
+	case models.QueuedTaskStatus:
+		return bp.TaskStatusQueued
+	case models.ProcessingTaskStatus:
+		return bp.TaskStatusProcessing
+	case models.ArchivedTaskStatus:
+		return bp.TaskStatusArchived
+	case models.FailedTaskStatus:
+		return bp.TaskStatusFailed
+	case models.CancelledTaskStatus:
+		return bp.TaskStatusCancelled
+	}
+
+	panic("invalid task status")
+}
+
+// convertArchiveFormat reports tasks created before archive formats were introduced as zip.
+func convertArchiveFormat(format models.ArchiveFormat) *bp.ArchiveFormat {
+	if format == "" {
+		format = models.ZipArchiveFormat
+	}
+
+	archiveFormat := bp.ArchiveFormat(format)
+	return &archiveFormat
+}
+
+func convertRequestFormat(format *bp.ArchiveFormat) models.ArchiveFormat {
+	if format == nil {
+		return ""
+	}
+
+	return models.ArchiveFormat(*format)
+}
+
+func convertTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func convertQueuePosition(position int) *int {
+	if position == 0 {
+		return nil
+	}
+
+	return &position
 }
 
 func convertLinks(links []*models.FileLink) []bp.FileLinkInfo {
 	fileLinksInfo := make([]bp.FileLinkInfo, 0, len(links))
 	for _, link := range links {
 		linkInfo := bp.FileLinkInfo{
-			Link:   link.Link,
-			Status: convertLinkStatus(link.Status),
+			Link:          link.Link,
+			Name:          link.Name,
+			ArchiveName:   link.ArchiveName,
+			Status:        convertLinkStatus(link.Status),
+			FailureReason: bp.FileLinkInfoFailureReason(link.FailureReason),
+			Error:         link.Error,
+			HttpStatus:    link.HTTPStatus,
+			BytesReceived: link.BytesReceived,
+			ContentType:   link.ContentType,
+			Attempts:      int(link.Attempts),
+			SizeLimit:     link.SizeLimit,
+			FinalUrl:      link.FinalURL,
+			Redirects:     link.Redirects,
 		}
 
 		fileLinksInfo = append(fileLinksInfo, linkInfo)
@@ -149,6 +301,7 @@ func convertRequestLink(links bp.AddLinkJSONRequestBody) []*models.FileLink {
 	for _, link := range links {
 		fileLinksInfo = append(fileLinksInfo, &models.FileLink{
 			Link: link.Link,
+			Name: link.Name,
 		})
 	}
 
@@ -29,10 +29,22 @@ func (h *Handler) handleError() echo.MiddlewareFunc {
 						Description: "not found",
 					})
 
+				case errors.Is(err, services.ErrInvalidTaskStatus):
+					return c.JSON(http.StatusConflict, bp.Error{
+						ErrorCode:   http.StatusConflict,
+						Description: err.Error(),
+					})
+
+				case errors.Is(err, services.ErrTaskStarted):
+					return c.JSON(http.StatusConflict, bp.Error{
+						ErrorCode:   http.StatusConflict,
+						Description: "task already started",
+					})
+
 				case errors.Is(err, services.ErrServiceBusy):
 					return c.JSON(http.StatusTooManyRequests, bp.Error{
 						ErrorCode:   http.StatusTooManyRequests,
-						Description: "service is busy",
+						Description: err.Error(),
 					})
 				default:
 					return c.JSON(http.StatusInternalServerError, bp.Error{
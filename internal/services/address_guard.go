@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"syscall"
+)
+
+var ErrBlockedAddress = errors.New("address is blocked")
+
+// AddressGuard keeps downloads away from the service own network. Loopback, link-local, private,
+// unspecified and multicast addresses are blocked together with the configured networks, unless
+// the address is in an allowed network.
+//
+// The guard checks the address a connection is actually made to, after the host is resolved, so
+// every redirect is checked too and a host that resolves to a public address once and to an
+// internal one later can not pass it.
+type AddressGuard struct {
+	blocked []netip.Prefix
+	allowed []netip.Prefix
+}
+
+// NewAddressGuard parses networks given as CIDRs or single addresses.
+func NewAddressGuard(blocked []string, allowed []string) (*AddressGuard, error) {
+	blockedPrefixes, err := parsePrefixes(blocked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse blocked networks: %w", err)
+	}
+
+	allowedPrefixes, err := parsePrefixes(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse allowed networks: %w", err)
+	}
+
+	return &AddressGuard{
+		blocked: blockedPrefixes,
+		allowed: allowedPrefixes,
+	}, nil
+}
+
+// Check returns ErrBlockedAddress if connections to the address are not allowed.
+func (g *AddressGuard) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if isInternalAddr(addr) {
+		return fmt.Errorf("%s: %w", addr, ErrBlockedAddress)
+	}
+
+	for _, prefix := range g.blocked {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%s: %w", addr, ErrBlockedAddress)
+		}
+	}
+
+	return nil
+}
+
+// Control is a net.Dialer control function, it is called with the resolved address before every connection.
+func (g *AddressGuard) Control(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %q: %w", address, err)
+	}
+
+	return g.Check(addrPort.Addr())
+}
+
+func isInternalAddr(addr netip.Addr) bool {
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified()
+}
+
+func parsePrefixes(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(network)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", network, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Admission limits the number of created (waiting for links) and active (queued or processing) tasks.
+// Each task holds at most one slot, which is released when the task finishes, is cancelled or abandoned.
+type Admission struct {
+	mu         sync.Mutex
+	created    map[string]time.Time
+	active     map[string]struct{}
+	maxCreated uint
+	maxActive  uint
+}
+
+// AdmissionUsage is a snapshot of the admission slots.
+type AdmissionUsage struct {
+	Created    int
+	MaxCreated int
+	Active     int
+	MaxActive  int
+}
+
+func NewAdmission(maxCreated, maxActive uint) *Admission {
+	return &Admission{
+		created:    make(map[string]time.Time),
+		active:     make(map[string]struct{}),
+		maxCreated: maxCreated,
+		maxActive:  maxActive,
+	}
+}
+
+// Create takes a created slot for the task returned by create.
+func (a *Admission) Create(create func() (string, error)) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if uint(len(a.created)) >= a.maxCreated {
+		return "", fmt.Errorf("created tasks limit %d reached: %w", a.maxCreated, ErrServiceBusy)
+	}
+
+	taskID, err := create()
+	if err != nil {
+		return "", err
+	}
+	a.created[taskID] = time.Now()
+
+	return taskID, nil
+}
+
+// Touch marks the created task as recently used, so it is not considered abandoned.
+func (a *Admission) Touch(taskID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.created[taskID]; ok {
+		a.created[taskID] = time.Now()
+	}
+}
+
+// Activate moves the task from the created slots to the active ones. It reports whether a new active
+// slot was taken, a task that is already active keeps its slot and must not be rolled back by the caller.
+func (a *Admission) Activate(taskID string) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.active[taskID]; ok {
+		return false, nil
+	}
+
+	if uint(len(a.active)) >= a.maxActive {
+		return false, fmt.Errorf("active tasks limit %d reached: %w", a.maxActive, ErrServiceBusy)
+	}
+
+	delete(a.created, taskID)
+	a.active[taskID] = struct{}{}
+
+	return true, nil
+}
+
+// Deactivate returns the active task to the created slots, it is used when the task could not be submitted.
+func (a *Admission) Deactivate(taskID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.active[taskID]; !ok {
+		return
+	}
+
+	delete(a.active, taskID)
+	a.created[taskID] = time.Now()
+}
+
+// Release frees the task slot.
+func (a *Admission) Release(taskID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.created, taskID)
+	delete(a.active, taskID)
+}
+
+// Restore takes a slot for the task found in the repository on start, limits are not checked
+// because the task was admitted before the restart.
+func (a *Admission) Restore(taskID string, active bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if active {
+		a.active[taskID] = struct{}{}
+		return
+	}
+
+	a.created[taskID] = time.Now()
+}
+
+// Abandoned returns created tasks that were not used for longer than timeout.
+func (a *Admission) Abandoned(timeout time.Duration) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deadline := time.Now().Add(-timeout)
+	taskIDs := make([]string, 0)
+	for taskID, usedAt := range a.created {
+		if usedAt.Before(deadline) {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+
+	return taskIDs
+}
+
+func (a *Admission) Usage() AdmissionUsage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return AdmissionUsage{
+		Created:    len(a.created),
+		MaxCreated: int(a.maxCreated),
+		Active:     len(a.active),
+		MaxActive:  int(a.maxActive),
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Archiver writes and reads archives of a single format.
+type Archiver interface {
+	// MIMEType is the content type of archives of the format.
+	MIMEType() string
+	// Extension is the file name extension of archives of the format, including the leading dot.
+	Extension() string
+	NewWriter(w io.Writer) ArchiveWriter
+	// Walk calls fn for every file in the archive in the archive order.
+	Walk(r io.ReaderAt, size int64, fn func(name string, size int64, r io.Reader) error) error
+}
+
+// ArchiveWriter adds files to an archive, the archive is complete only after Close.
+type ArchiveWriter interface {
+	Add(name string, size int64, r io.Reader) error
+	Close() error
+}
+
+// ArchiveFile is a file on disk that is put into an archive under Name.
+type ArchiveFile struct {
+	Name string
+	Path string
+}
+
+// ArchiveInfo describes a written archive.
+type ArchiveInfo struct {
+	Size int64
+	// SHA256 is a hex encoded checksum of the archive.
+	SHA256 string
+}
+
+// NewArchivers returns archivers of all supported formats.
+func NewArchivers(cfg config.Config) map[models.ArchiveFormat]Archiver {
+	return map[models.ArchiveFormat]Archiver{
+		models.ZipArchiveFormat:    NewZipper(cfg.ZipCompressionLevel, cfg.ZipStoredExtensions),
+		models.TarArchiveFormat:    NewTarrer(TarCompressionNone),
+		models.TarGzArchiveFormat:  NewTarrer(TarCompressionGzip),
+		models.TarZstArchiveFormat: NewTarrer(TarCompressionZstd),
+	}
+}
+
+// WriteArchive writes files into a temporary archive, syncs and verifies it and only then puts it
+// into the result store, so a partially written archive is never visible under name.
+func WriteArchive(
+	ctx context.Context,
+	archiver Archiver,
+	results ResultStore,
+	name string,
+	files []ArchiveFile,
+) (ArchiveInfo, error) {
+	archive, err := os.CreateTemp("", "archive-*")
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+
+	info, err := writeArchiveFile(archive, archiver, files)
+	if closeErr := archive.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close archive file: %w", closeErr)
+	}
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	if err := verifyArchive(archive.Name(), archiver, len(files)); err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to verify archive: %w", err)
+	}
+
+	if err := results.Put(ctx, name, archive.Name()); err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	return info, nil
+}
+
+// writeArchiveFile writes files as an archive into the file and syncs it to disk.
+func writeArchiveFile(archive *os.File, archiver Archiver, files []ArchiveFile) (ArchiveInfo, error) {
+	hash := sha256.New()
+	counter := &countingWriter{}
+	archiveWriter := archiver.NewWriter(io.MultiWriter(archive, hash, counter))
+
+	for _, file := range files {
+		if err := addFile(archiveWriter, file); err != nil {
+			return ArchiveInfo{}, fmt.Errorf("failed to add file %s: %w", file.Name, err)
+		}
+	}
+
+	if err := archiveWriter.Close(); err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to close archive writer: %w", err)
+	}
+
+	if err := archive.Sync(); err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to sync archive file: %w", err)
+	}
+
+	return ArchiveInfo{Size: counter.n, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+func addFile(w ArchiveWriter, file ArchiveFile) error {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return w.Add(file.Name, info.Size(), f)
+}
+
+// verifyArchive reads the written archive back, checking its structure and the checksums of all files.
+func verifyArchive(path string, archiver Archiver, files int) error {
+	archive, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+
+	var found int
+	err = archiver.Walk(archive, info.Size(), func(name string, size int64, r io.Reader) error {
+		found++
+
+		read, err := io.Copy(io.Discard, r)
+		if err != nil {
+			return fmt.Errorf("file %s: %w", name, err)
+		}
+		if read != size {
+			return fmt.Errorf("file %s has %d bytes, expected %d", name, read, size)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if found != files {
+		return fmt.Errorf("archive has %d files, expected %d", found, files)
+	}
+
+	return nil
+}
+
+// convertArchive rewrites the archive read from r in the format of the to archiver.
+func convertArchive(w io.Writer, to Archiver, from Archiver, r io.ReaderAt, size int64) error {
+	archiveWriter := to.NewWriter(w)
+
+	err := from.Walk(r, size, func(name string, size int64, r io.Reader) error {
+		return archiveWriter.Add(name, size, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	return archiveWriter.Close()
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+var ErrDisallowedContentType = errors.New("content type not allowed")
+
+var extensionMediaTypes = map[string][]string{
+	"jpg":  {"image/jpeg"},
+	"jpeg": {"image/jpeg"},
+	"png":  {"image/png"},
+	"gif":  {"image/gif"},
+	"webp": {"image/webp"},
+	"bmp":  {"image/bmp"},
+	"pdf":  {"application/pdf"},
+	"zip":  {"application/zip", "application/x-zip-compressed"},
+	"gz":   {"application/x-gzip", "application/gzip"},
+	"txt":  {"text/plain"},
+}
+
+// genericMediaTypes are sent by servers that do not know the file type, such files are judged by content only.
+var genericMediaTypes = []string{"", "application/octet-stream", "binary/octet-stream"}
+
+// ContentFilter checks downloaded files against the allowed extensions using
+// the response Content-Type header and the leading bytes of the body.
+type ContentFilter struct {
+	mediaTypes []string
+}
+
+func NewContentFilter(extensions []string) (*ContentFilter, error) {
+	mediaTypes := make([]string, 0, len(extensions))
+	for _, extension := range normalizeExtensions(extensions) {
+		if types, ok := extensionMediaTypes[extension]; ok {
+			mediaTypes = append(mediaTypes, types...)
+			continue
+		}
+
+		mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension("." + extension))
+		if err != nil {
+			return nil, fmt.Errorf("unknown media type for extension %q", extension)
+		}
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+
+	return &ContentFilter{mediaTypes: mediaTypes}, nil
+}
+
+// Check validates the Content-Type header and the sniffed type of the body head.
+func (f *ContentFilter) Check(contentType string, head []byte) error {
+	headerType := parseMediaType(contentType)
+	if !slices.Contains(genericMediaTypes, headerType) && !slices.Contains(f.mediaTypes, headerType) {
+		return fmt.Errorf("%w: Content-Type %q", ErrDisallowedContentType, headerType)
+	}
+
+	sniffedType := parseMediaType(http.DetectContentType(head))
+	if !slices.Contains(f.mediaTypes, sniffedType) {
+		return fmt.Errorf("%w: detected %q", ErrDisallowedContentType, sniffedType)
+	}
+
+	return nil
+}
+
+// normalizeExtensions lowercases extensions and strips their leading dot, so "PDF" and ".pdf" become "pdf".
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, 0, len(extensions))
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), "."))
+		if extension != "" {
+			normalized = append(normalized, extension)
+		}
+	}
+
+	return normalized
+}
+
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	return mediaType
+}
@@ -6,4 +6,7 @@ var (
 	ErrTaskNotFound = errors.New("task not found")
 	ErrValidation   = errors.New("validation error")
 	ErrServiceBusy  = errors.New("service is busy")
+	ErrTaskStarted  = errors.New("task already started")
+
+	ErrInvalidTaskStatus = errors.New("invalid task status transition")
 )
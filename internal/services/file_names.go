@@ -0,0 +1,174 @@
+package services
+
+import (
+	"270725/internal/models"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFileNameLen is the file name length in bytes most file systems support.
+const maxFileNameLen = 255
+
+// defaultFileName is used when neither the link nor the response provide a usable name.
+const defaultFileName = "file"
+
+// archiveFileNames returns the name of every downloaded file in the archive in the links order, failed
+// downloads get an empty name. A file is named by the name given with the link, the Content-Disposition
+// filename or the link path basename, in this order. The Content-Disposition filename keeps the
+// extension of the link, see withLinkExtension. Repeated names get a numeric suffix, so the first
+// file keeps its name and the others are numbered in the links order.
+func archiveFileNames(links []*models.FileLink, downloads []*DownloadResult) []string {
+	names := make([]string, len(downloads))
+	used := make(map[string]struct{}, len(downloads))
+
+	for idx, download := range downloads {
+		if download.Err != nil {
+			continue
+		}
+
+		var linkName string
+		if idx < len(links) {
+			linkName = links[idx].Name
+		}
+
+		name := firstFileName(linkName, withLinkExtension(download.FileName, download.Link), linkBaseName(download.Link))
+		names[idx] = uniqueFileName(name, used)
+	}
+
+	return names
+}
+
+// archiveFiles returns the downloaded files that are put into the archive under their names.
+func archiveFiles(names []string, downloads []*DownloadResult) []ArchiveFile {
+	files := make([]ArchiveFile, 0, len(downloads))
+	for idx, download := range downloads {
+		if names[idx] == "" {
+			continue
+		}
+
+		files = append(files, ArchiveFile{Name: names[idx], Path: download.Path})
+	}
+
+	return files
+}
+
+// firstFileName returns the first name that is not empty once sanitized.
+func firstFileName(names ...string) string {
+	for _, name := range names {
+		if name = sanitizeFileName(name); name != "" {
+			return name
+		}
+	}
+
+	return defaultFileName
+}
+
+// uniqueFileName returns the name with a numeric suffix before the extension if it is already used.
+// Names are compared case-insensitively, as archives are often extracted to such file systems.
+func uniqueFileName(name string, used map[string]struct{}) string {
+	extension := path.Ext(name)
+	stem := strings.TrimSuffix(name, extension)
+
+	candidate := name
+	for i := 1; ; i++ {
+		key := strings.ToLower(candidate)
+		if _, ok := used[key]; !ok {
+			used[key] = struct{}{}
+			return candidate
+		}
+
+		suffix := "_" + strconv.Itoa(i)
+		candidate = truncateFileName(stem, maxFileNameLen-len(suffix)-len(extension)) + suffix + extension
+	}
+}
+
+// withLinkExtension appends the link extension to a name given by the server unless the name already
+// has it. Only the link extension is checked against the allowed ones, so the server can not store a
+// file as "invoice.exe", it becomes "invoice.exe.pdf".
+func withLinkExtension(name string, link string) string {
+	name = sanitizeFileName(name)
+	extension := path.Ext(linkBaseName(link))
+	if name == "" || extension == "" || strings.EqualFold(path.Ext(name), extension) {
+		return name
+	}
+
+	return truncateFileName(name, maxFileNameLen-len(extension)) + extension
+}
+
+// linkBaseName returns the unescaped last element of the link path.
+func linkBaseName(link string) string {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return path.Base(linkURL.Path)
+}
+
+// contentDispositionFileName returns the filename parameter of the Content-Disposition header,
+// the extended filename* form is decoded by mime.ParseMediaType.
+func contentDispositionFileName(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+
+	return params["filename"]
+}
+
+// sanitizeFileName makes the name safe to extract on common file systems: directories are dropped,
+// control characters are removed, characters reserved on Windows are replaced and the name is cut to
+// maxFileNameLen bytes keeping its extension. The result is empty if nothing usable is left.
+func sanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	// Leading dots hide the file and trailing dots and spaces are dropped by Windows.
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return ""
+	}
+
+	extension := path.Ext(name)
+	if len(extension) >= maxFileNameLen/2 {
+		extension = ""
+	}
+
+	return truncateFileName(strings.TrimSuffix(name, extension), maxFileNameLen-len(extension)) + extension
+}
+
+// truncateFileName cuts the name to at most size bytes without splitting a character.
+func truncateFileName(name string, size int) string {
+	if len(name) <= size {
+		return name
+	}
+
+	for size > 0 && !utf8.RuneStart(name[size]) {
+		size--
+	}
+
+	return name[:size]
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minHostsSweep is the number of hosts the limiter keeps before idle hosts are first removed.
+const minHostsSweep = 256
+
+// HostLimit bounds requests to a single host. Zero MaxConnections or Rate is unlimited.
+type HostLimit struct {
+	// MaxConnections is the number of requests to the host in flight at once.
+	MaxConnections int
+	// Rate is the number of requests per second, Burst of them may be sent at once.
+	Rate  float64
+	Burst int
+}
+
+// HostOverride is the limit of hosts matching the glob Pattern, see LinkPolicy for the pattern syntax.
+type HostOverride struct {
+	Pattern string
+	Limit   HostLimit
+}
+
+// HostLimiter limits concurrent requests and the request rate per host. Hosts are told apart by
+// host and port, overrides are matched by the host name. Requests waiting for a host connection are
+// let in by turns of their download groups, so a task with many links to one host does not hold
+// back other tasks using the same host.
+type HostLimiter struct {
+	defaults  HostLimit
+	overrides []HostOverride
+
+	mu        sync.Mutex
+	hosts     map[string]*hostState
+	nextSweep int
+}
+
+type hostState struct {
+	limiter *rate.Limiter
+	slots   *fairSemaphore
+	users   int
+}
+
+func NewHostLimiter(defaults HostLimit, overrides []HostOverride) *HostLimiter {
+	normalized := make([]HostOverride, 0, len(overrides))
+	for _, override := range overrides {
+		override.Pattern = strings.ToLower(override.Pattern)
+		normalized = append(normalized, override)
+	}
+
+	return &HostLimiter{
+		defaults:  defaults,
+		overrides: normalized,
+		hosts:     make(map[string]*hostState),
+		nextSweep: minHostsSweep,
+	}
+}
+
+// ParseHostOverrides parses overrides given as host=connections:rate:burst, rate and burst may be omitted.
+func ParseHostOverrides(values []string) ([]HostOverride, error) {
+	overrides := make([]HostOverride, 0, len(values))
+	for _, value := range values {
+		pattern, limit, ok := strings.Cut(value, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid host limit %q, expected host=connections:rate:burst", value)
+		}
+
+		fields := strings.Split(limit, ":")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("invalid host limit %q, expected host=connections:rate:burst", value)
+		}
+
+		override := HostOverride{Pattern: pattern, Limit: HostLimit{Burst: 1}}
+		var err error
+		if override.Limit.MaxConnections, err = strconv.Atoi(fields[0]); err != nil || override.Limit.MaxConnections < 0 {
+			return nil, fmt.Errorf("invalid connections of host limit %q", value)
+		}
+		if len(fields) > 1 {
+			if override.Limit.Rate, err = strconv.ParseFloat(fields[1], 64); err != nil || override.Limit.Rate < 0 {
+				return nil, fmt.Errorf("invalid rate of host limit %q", value)
+			}
+		}
+		if len(fields) > 2 {
+			if override.Limit.Burst, err = strconv.Atoi(fields[2]); err != nil || override.Limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid burst of host limit %q", value)
+			}
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
+// Acquire waits for a connection slot of the host and a rate token, group is the download group
+// the request belongs to. The returned function releases the slot.
+func (l *HostLimiter) Acquire(ctx context.Context, host string, hostName string, group uint64) (func(), error) {
+	state := l.state(strings.ToLower(host), strings.ToLower(hostName))
+	done := func() {
+		l.mu.Lock()
+		state.users--
+		l.mu.Unlock()
+	}
+
+	if err := state.slots.acquire(ctx, group); err != nil {
+		done()
+		return nil, err
+	}
+
+	if err := state.limiter.Wait(ctx); err != nil {
+		state.slots.release()
+		done()
+		return nil, err
+	}
+
+	return sync.OnceFunc(func() {
+		state.slots.release()
+		done()
+	}), nil
+}
+
+func (l *HostLimiter) state(host string, hostName string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.hosts[host]
+	if !ok {
+		if len(l.hosts) >= l.nextSweep {
+			l.sweep()
+		}
+
+		limit := l.limit(hostName)
+		state = &hostState{
+			limiter: rate.NewLimiter(rateLimit(limit.Rate), max(limit.Burst, 1)),
+			slots:   newFairSemaphore(limit.MaxConnections),
+		}
+		l.hosts[host] = state
+	}
+	state.users++
+
+	return state
+}
+
+// sweep removes hosts without requests whose rate limiter is full, such a host behaves as a new one.
+func (l *HostLimiter) sweep() {
+	now := time.Now()
+	for host, state := range l.hosts {
+		if state.users == 0 && state.limiter.TokensAt(now) >= float64(state.limiter.Burst()) {
+			delete(l.hosts, host)
+		}
+	}
+
+	l.nextSweep = max(2*len(l.hosts), minHostsSweep)
+}
+
+func (l *HostLimiter) limit(hostName string) HostLimit {
+	for _, override := range l.overrides {
+		if matchHost(override.Pattern, hostName) {
+			return override.Limit
+		}
+	}
+
+	return l.defaults
+}
+
+func rateLimit(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(perSecond)
+}
+
+// fairSemaphore lets in at most limit holders, waiters are let in by turns of their groups and
+// in the arrival order within a group. A zero limit lets everyone in.
+type fairSemaphore struct {
+	limit int
+
+	mu      sync.Mutex
+	holders int
+	waiters map[uint64][]chan struct{}
+	turns   []uint64
+}
+
+func newFairSemaphore(limit int) *fairSemaphore {
+	return &fairSemaphore{
+		limit:   limit,
+		waiters: make(map[uint64][]chan struct{}),
+	}
+}
+
+func (s *fairSemaphore) acquire(ctx context.Context, group uint64) error {
+	if s.limit <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.holders < s.limit && len(s.turns) == 0 {
+		s.holders++
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	if len(s.waiters[group]) == 0 {
+		s.turns = append(s.turns, group)
+	}
+	s.waiters[group] = append(s.waiters[group], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.waiters[group]
+	idx := slices.Index(queue, ready)
+	if idx < 0 {
+		// The slot was handed over while the context was done, it is passed on to the next waiter.
+		s.releaseLocked()
+		return ctx.Err()
+	}
+
+	queue = slices.Delete(queue, idx, idx+1)
+	if len(queue) == 0 {
+		delete(s.waiters, group)
+		s.turns = slices.DeleteFunc(s.turns, func(turn uint64) bool { return turn == group })
+	} else {
+		s.waiters[group] = queue
+	}
+
+	return ctx.Err()
+}
+
+func (s *fairSemaphore) release() {
+	if s.limit <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.releaseLocked()
+}
+
+// releaseLocked hands the slot over to the first waiter of the group whose turn it is, the group
+// then moves to the end of the turns if it has more waiters.
+func (s *fairSemaphore) releaseLocked() {
+	if len(s.turns) == 0 {
+		s.holders--
+		return
+	}
+
+	group := s.turns[0]
+	s.turns = s.turns[1:]
+
+	queue := s.waiters[group]
+	ready := queue[0]
+	if len(queue) > 1 {
+		s.waiters[group] = queue[1:]
+		s.turns = append(s.turns, group)
+	} else {
+		delete(s.waiters, group)
+	}
+
+	close(ready)
+}
+
+// hostLimitedTransport sends requests through the host limiter, the connection slot is held until
+// the response body is closed.
+type hostLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *HostLimiter
+}
+
+type downloadGroupKey struct{}
+
+// withDownloadGroup marks requests made with the context as requests of the download group.
+func withDownloadGroup(ctx context.Context, group uint64) context.Context {
+	return context.WithValue(ctx, downloadGroupKey{}, group)
+}
+
+func (t *hostLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	group, _ := request.Context().Value(downloadGroupKey{}).(uint64)
+
+	release, err := t.limiter.Acquire(request.Context(), request.URL.Host, request.URL.Hostname(), group)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+
+	return response, nil
+}
+
+func (t *hostLimitedTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+
+	return err
+}
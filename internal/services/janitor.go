@@ -0,0 +1,111 @@
+package services
+
+import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Janitor periodically cancels abandoned tasks, deletes expired tasks with their archives
+// and keeps the total archives size under the configured quota.
+type Janitor struct {
+	log      *slog.Logger
+	tasks    *TaskService
+	quota    int64
+	interval time.Duration
+}
+
+func NewJanitor(cfg config.Config, log *slog.Logger, tasks *TaskService) *Janitor {
+	return &Janitor{
+		log:      log,
+		tasks:    tasks,
+		quota:    cfg.ArchivesQuota,
+		interval: cfg.JanitorInterval,
+	}
+}
+
+// Run cleans up tasks until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.Cleanup(ctx)
+		}
+	}
+}
+
+// Cleanup runs a single cleanup pass.
+func (j *Janitor) Cleanup(ctx context.Context) {
+	const op = "janitor.Cleanup"
+	log := j.log.With(slog.String("op", op))
+	log.Debug("start operation")
+
+	j.tasks.CancelAbandonedTasks(ctx)
+
+	tasks, err := j.tasks.GetAllTasks(ctx)
+	if err != nil {
+		log.Error("failed to get tasks", slog.String("error", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	archived := make([]*models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if !task.ExpiresAt.IsZero() && now.After(task.ExpiresAt) {
+			j.deleteTask(ctx, log, task.ID, "expired")
+			continue
+		}
+
+		if task.Status == models.ArchivedTaskStatus {
+			archived = append(archived, task)
+		}
+	}
+
+	if j.quota > 0 {
+		j.enforceQuota(ctx, log, archived)
+	}
+
+	log.Debug("operation completed")
+}
+
+// enforceQuota deletes the oldest archived tasks until archives fit into the quota.
+func (j *Janitor) enforceQuota(ctx context.Context, log *slog.Logger, archived []*models.Task) {
+	var total int64
+	for _, task := range archived {
+		total += task.ArchiveSize
+	}
+
+	slices.SortFunc(archived, func(a, b *models.Task) int {
+		return a.FinishedAt.Compare(b.FinishedAt)
+	})
+
+	for _, task := range archived {
+		if total <= j.quota {
+			return
+		}
+
+		if j.deleteTask(ctx, log, task.ID, "quota exceeded") {
+			total -= task.ArchiveSize
+		}
+	}
+}
+
+func (j *Janitor) deleteTask(ctx context.Context, log *slog.Logger, taskID, reason string) bool {
+	if err := j.tasks.DeleteTask(ctx, taskID); err != nil && !errors.Is(err, ErrTaskNotFound) {
+		log.Error("failed to delete task", slog.String("task_id", taskID), slog.String("error", err.Error()))
+		return false
+	}
+
+	log.Info("task deleted", slog.String("task_id", taskID), slog.String("reason", reason))
+
+	return true
+}
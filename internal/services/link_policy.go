@@ -0,0 +1,94 @@
+package services
+
+import (
+	"270725/internal/config"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var ErrLinkNotAllowed = errors.New("link is not allowed")
+
+// LinkPolicy restricts the schemes and hosts links are downloaded from. Host patterns are globs where
+// "*" matches any part of the host name, so "*.example.com" matches "cdn.example.com" and
+// "a.cdn.example.com" but not "example.com". Denied hosts win over allowed ones, an empty allow
+// list allows all hosts that are not denied.
+type LinkPolicy struct {
+	schemes      []string
+	allowedHosts []string
+	deniedHosts  []string
+}
+
+func NewLinkPolicy(cfg config.Filter) *LinkPolicy {
+	return &LinkPolicy{
+		schemes:      normalizeList(cfg.AllowedSchemes),
+		allowedHosts: normalizeList(cfg.AllowedHosts),
+		deniedHosts:  normalizeList(cfg.DeniedHosts),
+	}
+}
+
+// Check returns ErrLinkNotAllowed if the link scheme or host is not allowed.
+func (p *LinkPolicy) Check(link *url.URL) error {
+	scheme := strings.ToLower(link.Scheme)
+	if !slices.Contains(p.schemes, scheme) {
+		return fmt.Errorf("scheme %q: %w", link.Scheme, ErrLinkNotAllowed)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(link.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("empty host: %w", ErrLinkNotAllowed)
+	}
+
+	if matchHosts(p.deniedHosts, host) {
+		return fmt.Errorf("host %q is denied: %w", host, ErrLinkNotAllowed)
+	}
+
+	if len(p.allowedHosts) > 0 && !matchHosts(p.allowedHosts, host) {
+		return fmt.Errorf("host %q is not allowed: %w", host, ErrLinkNotAllowed)
+	}
+
+	return nil
+}
+
+func matchHosts(patterns []string, host string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return matchHost(pattern, host)
+	})
+}
+
+// matchHost reports whether the host matches the glob pattern, "*" is the only special character.
+func matchHost(pattern string, host string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == host
+	}
+
+	if !strings.HasPrefix(host, parts[0]) {
+		return false
+	}
+	host = host[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(host, part)
+		if idx < 0 {
+			return false
+		}
+		host = host[idx+len(part):]
+	}
+
+	return strings.HasSuffix(host, parts[len(parts)-1])
+}
+
+func normalizeList(values []string) []string {
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+		if value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+
+	return normalized
+}
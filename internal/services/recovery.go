@@ -0,0 +1,79 @@
+package services
+
+import (
+	"270725/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// recoverTasks restores the service state from the repository after a restart. Tasks interrupted
+// in the middle of processing lose their partial archive and are queued again, queued tasks missing
+// from the queue are pushed back, and all unfinished tasks get their admission slots.
+func (t *TaskService) recoverTasks(ctx context.Context) error {
+	const op = "taskService.recoverTasks"
+	log := t.log.With(slog.String("op", op))
+	log.Debug("start operation")
+
+	tasks, err := t.taskRepo.GetAllTasks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	for _, task := range tasks {
+		switch task.Status {
+		case models.CreatedTaskStatus:
+			t.admission.Restore(task.ID, false)
+
+		case models.ProcessingTaskStatus:
+			t.removeArchive(ctx, log, task.ID)
+			if err := t.requeueTask(ctx, task.ID); err != nil {
+				return fmt.Errorf("failed to requeue task %s: %w", task.ID, err)
+			}
+			t.admission.Restore(task.ID, true)
+			log.Info("interrupted task queued again", slog.String("task_id", task.ID))
+
+		case models.QueuedTaskStatus:
+			position, err := t.queue.Position(ctx, task.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get queue position of task %s: %w", task.ID, err)
+			}
+
+			if position == 0 {
+				if err := t.queue.Push(ctx, task.ID); err != nil {
+					return fmt.Errorf("failed to queue task %s: %w", task.ID, err)
+				}
+			}
+			t.admission.Restore(task.ID, true)
+		}
+	}
+
+	log.Debug("operation completed")
+
+	return nil
+}
+
+// requeueTask resets the interrupted task and its links to the queued state and pushes it to the queue.
+func (t *TaskService) requeueTask(ctx context.Context, taskID string) error {
+	_, err := t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if task.Status != models.ProcessingTaskStatus {
+			return fmt.Errorf("%s -> %s: %w", task.Status, models.QueuedTaskStatus, ErrInvalidTaskStatus)
+		}
+
+		task.Status = models.QueuedTaskStatus
+		for idx, link := range task.FilesLink {
+			task.FilesLink[idx] = &models.FileLink{
+				Link:   link.Link,
+				Name:   link.Name,
+				Status: models.NewTaskLinkStatus,
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return wrapRepoError(err)
+	}
+
+	return t.queue.Push(ctx, taskID)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"270725/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrRedirectNotAllowed = errors.New("redirect is not allowed")
+
+// RedirectPolicy decides which redirects a download follows.
+type RedirectPolicy struct {
+	// MaxHops is the number of redirects followed, 0 follows none.
+	MaxHops uint
+	// SameHost refuses redirects to a host other than the host of the link.
+	SameHost bool
+	// AllowDowngrade follows redirects from https to http.
+	AllowDowngrade bool
+}
+
+func NewRedirectPolicy(cfg config.TaskConfig) RedirectPolicy {
+	return RedirectPolicy{
+		MaxHops:        cfg.RedirectMaxHops,
+		SameHost:       cfg.RedirectSameHost,
+		AllowDowngrade: cfg.RedirectAllowDowngrade,
+	}
+}
+
+// Check is an http.Client CheckRedirect function, via holds the requests made so far, the oldest first.
+func (p RedirectPolicy) Check(request *http.Request, via []*http.Request) error {
+	if uint(len(via)) > p.MaxHops {
+		return fmt.Errorf("more than %d redirects: %w", p.MaxHops, ErrRedirectNotAllowed)
+	}
+
+	link := via[0].URL
+	if p.SameHost && !strings.EqualFold(request.URL.Hostname(), link.Hostname()) {
+		return fmt.Errorf("redirect from host %q to %q: %w", link.Hostname(), request.URL.Hostname(), ErrRedirectNotAllowed)
+	}
+
+	previous := via[len(via)-1].URL
+	if !p.AllowDowngrade && strings.EqualFold(previous.Scheme, "https") && !strings.EqualFold(request.URL.Scheme, "https") {
+		return fmt.Errorf("redirect from https to %s: %w", request.URL.Scheme, ErrRedirectNotAllowed)
+	}
+
+	return nil
+}
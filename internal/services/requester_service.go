@@ -1,86 +1,349 @@
 package services
 
 import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/alitto/pond/v2"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
 )
 
 type Requester struct {
-	client *http.Client
-	pool   pond.Pool
+	client      *http.Client
+	pool        pond.Pool
+	filter      *ContentFilter
+	links       *LinkPolicy
+	redirect    RedirectPolicy
+	retry       RetryPolicy
+	timeout     time.Duration
+	maxFileSize int64
+	maxTaskSize int64
+	groups      atomic.Uint64
 }
 
-type responseInfo struct {
-	link   string
-	result []byte
+// DownloadResult describes a single link download. The body is stored in a temporary
+// file at Path, which the caller must remove when it is no longer needed.
+type DownloadResult struct {
+	Link        string
+	Path        string
+	Size        int64
+	StatusCode  int
+	ContentType string
+	Attempts    uint
+	Err         error
+
+	// FileName is the Content-Disposition filename of the response.
+	FileName string
+	// FinalURL is the URL the response came from after redirects.
+	FinalURL string
+	// Redirects are the URLs the link was redirected to in order, including a refused one.
+	Redirects []string
 }
 
-func NewRequesterService(poolSize int) *Requester {
-	return &Requester{
-		client: &http.Client{},
-		pool:   pond.NewPool(poolSize, pond.WithNonBlocking(true)),
+// DownloadError is a failed link download together with the reason it failed.
+type DownloadError struct {
+	Reason     models.LinkFailureReason
+	StatusCode int
+	RetryAfter time.Duration
+	// Limit is the exceeded byte limit of a size_limit failure.
+	Limit int64
+	Err   error
+}
+
+func (e *DownloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+func NewRequesterService(cfg config.Config) (*Requester, error) {
+	filter, err := NewContentFilter(cfg.AllowedExtensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content filter: %w", err)
+	}
+
+	guard, err := NewAddressGuard(cfg.BlockedNetworks, cfg.AllowedNetworks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address guard: %w", err)
 	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The guard checks the address the dialer connects to, a proxy would hide the real destination from it.
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.DownloadConnectTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   guard.Control,
+	}).DialContext
+	transport.TLSHandshakeTimeout = cfg.DownloadConnectTimeout
+	transport.ResponseHeaderTimeout = cfg.DownloadHeaderTimeout
+
+	overrides, err := ParseHostOverrides(cfg.HostLimits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host limits: %w", err)
+	}
+	hostLimiter := NewHostLimiter(HostLimit{
+		MaxConnections: int(cfg.HostMaxConnections),
+		Rate:           cfg.HostRateLimit,
+		Burst:          int(cfg.HostRateBurst),
+	}, overrides)
+
+	return &Requester{
+		client:      &http.Client{Transport: &hostLimitedTransport{base: transport, limiter: hostLimiter}},
+		pool:        pond.NewPool(int(cfg.TasksBufferSize*cfg.LinksInTask), pond.WithNonBlocking(true)),
+		filter:      filter,
+		links:       NewLinkPolicy(cfg.Filter),
+		redirect:    NewRedirectPolicy(cfg.TaskConfig),
+		retry:       NewRetryPolicy(cfg.TaskConfig),
+		timeout:     cfg.DownloadTimeout,
+		maxFileSize: cfg.MaxFileSize,
+		maxTaskSize: cfg.MaxTaskSize,
+	}, nil
 }
 
-func (r *Requester) GetLinksContents(log *slog.Logger, links []string) map[string][]byte {
-	resultsChan := make(chan responseInfo)
+// GetLinksContents downloads links into temporary files. Results are returned in the links order.
+// The links share the task byte limit and take turns with links of other calls for busy hosts.
+// Cancelling ctx aborts in-flight downloads.
+func (r *Requester) GetLinksContents(ctx context.Context, log *slog.Logger, links []string) []*DownloadResult {
+	ctx = withDownloadGroup(ctx, r.groups.Add(1))
+	budget := newSizeBudget(r.maxTaskSize)
+	results := make([]*DownloadResult, len(links))
 	tasks := make([]pond.Task, 0, len(links))
-	for _, link := range links {
+	for idx, link := range links {
+		results[idx] = &DownloadResult{Link: link}
+
 		task := r.pool.Submit(func() {
-			data, err := r.request(link)
-			if err != nil {
+			result := results[idx]
+			if err := r.download(ctx, result, budget); err != nil {
+				result.Err = err
 				log.Error("failed to send request", slog.String("link", link), slog.String("error", err.Error()))
-				return
 			}
-
-			resultsChan <- responseInfo{link: link, result: data}
 		})
 
 		tasks = append(tasks, task)
 	}
 
-	go func() {
-		for _, task := range tasks {
-			if err := task.Wait(); err != nil {
-				log.Error("failed to wait task", slog.String("error", err.Error()))
-			}
+	for idx, task := range tasks {
+		if err := task.Wait(); err != nil {
+			log.Error("failed to wait task", slog.String("error", err.Error()))
+			results[idx].Err = &DownloadError{Reason: models.InternalFailureReason, Err: err}
 		}
+	}
 
-		close(resultsChan)
-	}()
+	return results
+}
+
+// Shutdown stops accepting downloads and waits for the running ones to finish or ctx to be done.
+// Running downloads are aborted by cancelling their own contexts.
+func (r *Requester) Shutdown(ctx context.Context) error {
+	defer r.client.CloseIdleConnections()
 
-	results := make(map[string][]byte)
-	for taskInfo := range resultsChan {
-		results[taskInfo.link] = taskInfo.result
+	select {
+	case <-r.pool.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait running downloads: %w", ctx.Err())
 	}
+}
 
-	return results
+func (r *Requester) download(ctx context.Context, result *DownloadResult, budget *sizeBudget) error {
+	for attempt := uint(1); ; attempt++ {
+		result.Attempts = attempt
 
+		err := r.tryDownload(ctx, result, budget)
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := r.retry.next(attempt, err)
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &DownloadError{Reason: models.CancelledFailureReason, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
 }
 
-func (r *Requester) request(link string) ([]byte, error) {
-	request, err := http.NewRequest(http.MethodGet, link, nil)
+func (r *Requester) tryDownload(ctx context.Context, result *DownloadResult, budget *sizeBudget) (err error) {
+	result.StatusCode, result.ContentType, result.FileName, result.Size = 0, "", "", 0
+	result.FinalURL, result.Redirects = "", nil
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, result.Link, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return &DownloadError{Reason: models.InternalFailureReason, Err: fmt.Errorf("failed to create request: %w", err)}
 	}
 
-	response, err := r.client.Do(request)
+	// The client is copied to record redirects of this download, the transport is shared.
+	client := *r.client
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		result.Redirects = append(result.Redirects, request.URL.String())
+		if err := r.redirect.Check(request, via); err != nil {
+			return err
+		}
+
+		// The link itself is checked when it is added to a task.
+		return r.links.Check(request.URL)
+	}
+
+	response, err := client.Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return &DownloadError{Reason: failureReason(err), Err: fmt.Errorf("failed to send request: %w", err)}
 	}
 	defer response.Body.Close()
 
+	result.StatusCode = response.StatusCode
+	result.FinalURL = response.Request.URL.String()
+	result.ContentType = response.Header.Get("Content-Type")
+	result.FileName = contentDispositionFileName(response.Header.Get("Content-Disposition"))
+
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status code %d", response.StatusCode)
+		return &DownloadError{
+			Reason:     models.HTTPStatusFailureReason,
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			Err:        fmt.Errorf("request failed with status code %d", response.StatusCode),
+		}
+	}
+
+	if r.maxFileSize > 0 && response.ContentLength > r.maxFileSize {
+		return sizeLimitDownloadError(&SizeLimitError{Limit: r.maxFileSize, Err: ErrFileTooLarge})
 	}
+	if !budget.fits(response.ContentLength) {
+		return sizeLimitDownloadError(&SizeLimitError{Limit: budget.limit, Err: ErrTaskTooLarge})
+	}
+
+	body := &limitedReader{r: response.Body, fileLimit: r.maxFileSize, budget: budget}
+	defer func() {
+		if err != nil {
+			budget.release(body.n)
+		}
+	}()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return readDownloadError(err)
+	}
+	head = head[:n]
+	result.Size = int64(n)
 
-	body, err := io.ReadAll(response.Body)
+	if err := r.filter.Check(result.ContentType, head); err != nil {
+		return &DownloadError{Reason: models.DisallowedTypeFailureReason, Err: err}
+	}
+
+	file, err := os.CreateTemp("", "download-*")
+	if err != nil {
+		return &DownloadError{Reason: models.InternalFailureReason, Err: fmt.Errorf("failed to create temp file: %w", err)}
+	}
+
+	result.Size, err = io.Copy(file, io.MultiReader(bytes.NewReader(head), body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return readDownloadError(err)
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return &DownloadError{Reason: models.InternalFailureReason, Err: fmt.Errorf("failed to write temp file: %w", err)}
+	}
+	result.Path = file.Name()
+
+	return nil
+}
+
+// readDownloadError wraps an error of reading the response body.
+func readDownloadError(err error) *DownloadError {
+	var limitErr *SizeLimitError
+	if errors.As(err, &limitErr) {
+		return sizeLimitDownloadError(limitErr)
+	}
+
+	return &DownloadError{Reason: failureReason(err), Err: fmt.Errorf("failed to read response body: %w", err)}
+}
+
+func sizeLimitDownloadError(err *SizeLimitError) *DownloadError {
+	return &DownloadError{Reason: models.SizeLimitFailureReason, Limit: err.Limit, Err: err}
+}
+
+// failureReason classifies transport errors returned by the http client.
+func failureReason(err error) models.LinkFailureReason {
+	if errors.Is(err, context.Canceled) {
+		return models.CancelledFailureReason
 	}
 
-	return body, nil
+	if errors.Is(err, ErrBlockedAddress) {
+		return models.BlockedAddressFailureReason
+	}
+
+	if errors.Is(err, ErrLinkNotAllowed) {
+		return models.DisallowedLinkFailureReason
+	}
+
+	if errors.Is(err, ErrRedirectNotAllowed) {
+		return models.RedirectFailureReason
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return models.DNSFailureReason
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return models.TimeoutFailureReason
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return models.ConnectFailureReason
+	}
+
+	return models.NetworkFailureReason
+}
+
+// downloadFailureReason returns the reason of a failed download.
+func downloadFailureReason(err error) models.LinkFailureReason {
+	var downloadErr *DownloadError
+	if errors.As(err, &downloadErr) {
+		return downloadErr.Reason
+	}
+
+	return models.InternalFailureReason
+}
+
+// RemoveDownloads deletes temporary files of the downloaded links.
+func RemoveDownloads(log *slog.Logger, results []*DownloadResult) {
+	for _, result := range results {
+		if result.Path == "" {
+			continue
+		}
+
+		if err := os.Remove(result.Path); err != nil && !os.IsNotExist(err) {
+			log.Error("failed to remove downloaded file", slog.String("path", result.Path), slog.String("error", err.Error()))
+		}
+	}
 }
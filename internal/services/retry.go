@@ -0,0 +1,106 @@
+package services
+
+import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// retryableStatuses are response codes that usually indicate a transient failure of the origin.
+var retryableStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy decides whether a failed download is attempted again and how long to wait before it.
+// Only GET requests are sent, so every attempt is idempotent.
+type RetryPolicy struct {
+	MaxAttempts uint
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Jitter      float64
+}
+
+func NewRetryPolicy(cfg config.TaskConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: max(cfg.DownloadAttempts, 1),
+		Backoff:     cfg.DownloadRetryBackoff,
+		MaxBackoff:  cfg.DownloadRetryMaxBackoff,
+		Jitter:      cfg.DownloadRetryJitter,
+	}
+}
+
+// next returns the delay before the next attempt, false means the download must not be retried.
+func (p RetryPolicy) next(attempt uint, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	var downloadErr *DownloadError
+	if !errors.As(err, &downloadErr) || !isRetryable(downloadErr) {
+		return 0, false
+	}
+
+	delay := p.backoff(attempt)
+	if downloadErr.RetryAfter > 0 {
+		if downloadErr.RetryAfter > p.MaxBackoff {
+			return 0, false
+		}
+
+		delay = max(delay, downloadErr.RetryAfter)
+	}
+
+	return delay, true
+}
+
+func (p RetryPolicy) backoff(attempt uint) time.Duration {
+	delay := p.Backoff
+	for i := uint(1); i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(float64(delay) * p.Jitter * rand.Float64())
+	}
+
+	return delay
+}
+
+func isRetryable(err *DownloadError) bool {
+	switch err.Reason {
+	case models.TimeoutFailureReason, models.ConnectFailureReason, models.NetworkFailureReason:
+		return true
+	case models.HTTPStatusFailureReason:
+		return slices.Contains(retryableStatuses, err.StatusCode)
+	}
+
+	return false
+}
+
+// parseRetryAfter parses Retry-After header given either in seconds or as HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+var (
+	ErrFileTooLarge = errors.New("file is too large")
+	ErrTaskTooLarge = errors.New("task files are too large")
+)
+
+// SizeLimitError is a download that exceeded the file or the task byte limit.
+type SizeLimitError struct {
+	Limit int64
+	Err   error
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("%s: limit is %d bytes", e.Err, e.Limit)
+}
+
+func (e *SizeLimitError) Unwrap() error {
+	return e.Err
+}
+
+// sizeBudget is the number of bytes all downloads of a task may read together, a zero limit is unlimited.
+type sizeBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+func newSizeBudget(limit int64) *sizeBudget {
+	return &sizeBudget{limit: limit}
+}
+
+// take counts n more bytes and reports whether the budget still holds them.
+func (b *sizeBudget) take(n int64) bool {
+	used := b.used.Add(n)
+	return b.limit <= 0 || used <= b.limit
+}
+
+// release returns bytes of a failed download attempt, they are not kept in the task.
+func (b *sizeBudget) release(n int64) {
+	b.used.Add(-n)
+}
+
+// fits reports whether n more bytes fit into the budget.
+func (b *sizeBudget) fits(n int64) bool {
+	return b.limit <= 0 || b.used.Load()+n <= b.limit
+}
+
+// limitedReader fails reads once more than fileLimit bytes are read or the task budget is exhausted.
+// All read bytes are taken from the budget, n of them are released if the download fails.
+type limitedReader struct {
+	r         io.Reader
+	fileLimit int64
+	budget    *sizeBudget
+	n         int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	fits := l.budget.take(int64(n))
+
+	if l.fileLimit > 0 && l.n > l.fileLimit {
+		return n, &SizeLimitError{Limit: l.fileLimit, Err: ErrFileTooLarge}
+	}
+	if !fits {
+		return n, &SizeLimitError{Limit: l.budget.limit, Err: ErrTaskTooLarge}
+	}
+
+	return n, err
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"time"
+)
+
+// TarCompression is the compression applied to the whole tar stream.
+type TarCompression int
+
+const (
+	TarCompressionNone TarCompression = iota
+	TarCompressionGzip
+	TarCompressionZstd
+)
+
+type Tarrer struct {
+	compression TarCompression
+}
+
+func NewTarrer(compression TarCompression) *Tarrer {
+	return &Tarrer{
+		compression: compression,
+	}
+}
+
+func (t *Tarrer) MIMEType() string {
+	switch t.compression {
+	case TarCompressionGzip:
+		return "application/gzip"
+	case TarCompressionZstd:
+		return "application/zstd"
+	default:
+		return "application/x-tar"
+	}
+}
+
+func (t *Tarrer) Extension() string {
+	switch t.compression {
+	case TarCompressionGzip:
+		return ".tar.gz"
+	case TarCompressionZstd:
+		return ".tar.zst"
+	default:
+		return ".tar"
+	}
+}
+
+func (t *Tarrer) NewWriter(w io.Writer) ArchiveWriter {
+	writer := &tarWriter{}
+
+	switch t.compression {
+	case TarCompressionGzip:
+		writer.compressor = gzip.NewWriter(w)
+	case TarCompressionZstd:
+		// The encoder fails only on invalid options, so the error is reported on write.
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			writer.err = fmt.Errorf("failed to create zstd encoder: %w", err)
+			return writer
+		}
+		writer.compressor = encoder
+	}
+
+	if writer.compressor != nil {
+		w = writer.compressor
+	}
+	writer.writer = tar.NewWriter(w)
+
+	return writer
+}
+
+func (t *Tarrer) Walk(r io.ReaderAt, size int64, fn func(name string, size int64, r io.Reader) error) error {
+	var reader io.Reader = io.NewSectionReader(r, 0, size)
+
+	switch t.compression {
+	case TarCompressionGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case TarCompressionZstd:
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := fn(header.Name, header.Size, tarReader); err != nil {
+			return err
+		}
+	}
+
+	// The compressed stream is read to the end to check its trailing checksum.
+	_, err := io.Copy(io.Discard, reader)
+	return err
+}
+
+type tarWriter struct {
+	writer     *tar.Writer
+	compressor io.WriteCloser
+	err        error
+}
+
+func (w *tarWriter) Add(name string, size int64, r io.Reader) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  time.Now(),
+	}
+	if err := w.writer.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(w.writer, r)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+
+	return nil
+}
@@ -7,14 +7,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/alitto/pond/v2"
 	"github.com/go-playground/validator/v10"
+	"io"
 	"log/slog"
 	"net/url"
-	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type TaskRepository interface {
@@ -22,68 +24,172 @@ type TaskRepository interface {
 	GetAllTasks(ctx context.Context) ([]*models.Task, error)
 	GetTask(ctx context.Context, id string) (*models.Task, error)
 	AddLinksToTask(ctx context.Context, taskID string, links []*models.FileLink) (*models.Task, error)
+	UpdateTask(ctx context.Context, taskID string, update func(task *models.Task) error) (*models.Task, error)
+	DeleteTask(ctx context.Context, taskID string) error
 	MarkTaskLinksInProcessStatus(ctx context.Context, taskID string) error
 	MarkTaskLinksCompleted(ctx context.Context, taskID string, completedLinks []string) error
 }
 
+// TaskQueue is a FIFO queue of tasks waiting for a worker.
+type TaskQueue interface {
+	Push(ctx context.Context, taskID string) error
+	Pop(ctx context.Context) (string, error)
+	Remove(ctx context.Context, taskID string) error
+	Position(ctx context.Context, taskID string) (int, error)
+}
+
 type RequesterClient interface {
-	GetLinksContents(log *slog.Logger, links []string) map[string][]byte
+	GetLinksContents(ctx context.Context, log *slog.Logger, links []string) []*DownloadResult
 }
 
-type Archiver interface {
-	ToArchive(archiveName string, files map[string][]byte) error
+// ResultStore keeps task archives, it is shared by the archiver writing archives and the service reading them.
+type ResultStore interface {
+	// Put stores the file at path under name, the file may be moved by the store.
+	Put(ctx context.Context, name string, path string) error
+	Get(ctx context.Context, name string) (storage.ResultReader, error)
+	Stat(ctx context.Context, name string) (storage.ResultInfo, error)
+	Delete(ctx context.Context, name string) error
+	// Presign returns a temporary link to download the result as fileName.
+	Presign(ctx context.Context, name string, fileName string, expires time.Duration) (string, error)
+}
+
+// TaskResult is the archive of an archived task.
+type TaskResult struct {
+	// Name is the archive file name including the format extension.
+	Name     string
+	MIMEType string
+	ModTime  time.Time
+	// ArchiveInfo is empty when the archive is converted to another format on download.
+	ArchiveInfo
+	// URL is a presigned link to the archive, the archive is served by redirect when it is set.
+	URL string
+	// Content streams the archive when URL is empty, the caller must close it.
+	// The stored archive is also an io.ReadSeeker, a converted one is not.
+	Content io.ReadCloser
 }
 
 type TaskService struct {
 	log               *slog.Logger
 	taskRepo          TaskRepository
+	queue             TaskQueue
 	requester         RequesterClient
-	archiver          Archiver
-	taskInProcess     atomic.Int64
-	maxTasks          uint
+	archivers         map[models.ArchiveFormat]Archiver
+	archiveFormat     models.ArchiveFormat
+	admission         *Admission
+	createdTimeout    time.Duration
 	linksInFile       uint
 	validator         *validator.Validate
-	pool              pond.Pool
 	allowedExtensions []string
-	archivesDir       string
+	linkPolicy        *LinkPolicy
+	results           ResultStore
+	resultRedirect    bool
+	presignTTL        time.Duration
+	autoStart         bool
+	archiveTTL        time.Duration
+	ctx               context.Context
+	stop              context.CancelFunc
+	workersCtx        context.Context
+	stopWorkers       context.CancelFunc
+	workersWG         sync.WaitGroup
+	closed            atomic.Bool
+	runningMu         sync.Mutex
+	running           map[string]context.CancelFunc
+	workers           uint
 }
 
 func NewTaskService(
 	cfg config.Config,
 	log *slog.Logger,
 	taskRepository TaskRepository,
+	queue TaskQueue,
 	requester RequesterClient,
-	archiver Archiver,
+	archivers map[models.ArchiveFormat]Archiver,
+	results ResultStore,
 ) *TaskService {
+	ctx, stop := context.WithCancel(context.Background())
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+
 	return &TaskService{
 		log:               log,
 		taskRepo:          taskRepository,
+		queue:             queue,
 		requester:         requester,
-		archiver:          archiver,
-		taskInProcess:     atomic.Int64{},
-		maxTasks:          cfg.TasksBufferSize,
+		archivers:         archivers,
+		archiveFormat:     models.ArchiveFormat(cfg.ArchiveFormat),
+		admission:         NewAdmission(cfg.MaxCreatedTasks, cfg.TasksBufferSize+cfg.MaxQueuedTasks),
+		createdTimeout:    cfg.CreatedTaskTimeout,
 		linksInFile:       cfg.LinksInTask,
 		validator:         validator.New(),
-		pool:              pond.NewPool(int(cfg.TasksBufferSize), pond.WithNonBlocking(true)),
-		allowedExtensions: cfg.AllowedExtensions,
-		archivesDir:       cfg.ArchivesDir,
+		allowedExtensions: normalizeExtensions(cfg.AllowedExtensions),
+		linkPolicy:        NewLinkPolicy(cfg.Filter),
+		results:           results,
+		resultRedirect:    cfg.ResultRedirect,
+		presignTTL:        cfg.ResultPresignTTL,
+		autoStart:         cfg.AutoStartTask,
+		archiveTTL:        cfg.ArchiveTTL,
+		ctx:               ctx,
+		stop:              stop,
+		workersCtx:        workersCtx,
+		stopWorkers:       stopWorkers,
+		running:           make(map[string]context.CancelFunc),
+		workers:           cfg.TasksBufferSize,
+	}
+}
+
+// Start recovers tasks interrupted by a previous shutdown or crash and starts queue workers.
+func (t *TaskService) Start(ctx context.Context) error {
+	if err := t.recoverTasks(ctx); err != nil {
+		return fmt.Errorf("failed to recover tasks: %w", err)
+	}
+
+	for range t.workers {
+		t.workersWG.Add(1)
+		go t.runWorker()
 	}
+
+	return nil
 }
 
-func (t *TaskService) NewTask(ctx context.Context) (string, error) {
+// NewTask creates a task whose archive is written in the format, an empty format selects the configured one.
+func (t *TaskService) NewTask(ctx context.Context, format models.ArchiveFormat) (string, error) {
 	const op = "taskService.NewTask"
 	log := t.log.With(slog.String("op", op))
 	log.Debug("start operation")
 
-	if t.taskInProcess.Load() > int64(t.maxTasks) {
-		return "", ErrServiceBusy
+	if t.closed.Load() {
+		return "", fmt.Errorf("service is shutting down: %w", ErrServiceBusy)
+	}
+
+	if format == "" {
+		format = t.archiveFormat
+	}
+	if _, ok := t.archivers[format]; !ok {
+		return "", fmt.Errorf("archive format %q is not supported: %w", format, ErrValidation)
 	}
 
-	taskID, err := t.taskRepo.NewTask(ctx)
+	taskID, err := t.admission.Create(func() (string, error) {
+		taskID, err := t.taskRepo.NewTask(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to add new task: %w", err)
+		}
+
+		_, err = t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+			task.ArchiveFormat = format
+			return nil
+		})
+		if err != nil {
+			if err := t.taskRepo.DeleteTask(ctx, taskID); err != nil {
+				log.Error("failed to delete task", slog.String("task_id", taskID), slog.String("error", err.Error()))
+			}
+
+			return "", fmt.Errorf("failed to set task archive format: %w", err)
+		}
+
+		return taskID, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to add new task: %w", err)
+		return "", err
 	}
-	t.taskInProcess.Add(1)
 
 	log.Debug("operation completed")
 
@@ -121,7 +227,7 @@ func (t *TaskService) GetTask(ctx context.Context, id string) (*models.Task, err
 
 	log.Debug("operation completed")
 
-	return task, nil
+	return t.withQueuePosition(ctx, task), nil
 }
 
 func (t *TaskService) AddLinksToTask(ctx context.Context, taskID string, links []*models.FileLink) (*models.Task, error) {
@@ -138,37 +244,110 @@ func (t *TaskService) AddLinksToTask(ctx context.Context, taskID string, links [
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	if len(links)+len(task.FilesLink) > int(t.linksInFile) {
-		return nil, fmt.Errorf("max tasks reached: %w", ErrValidation)
+	if err := t.checkTaskAcceptsLinks(task, len(links)); err != nil {
+		return nil, err
 	}
 
-	if err := t.validator.Struct(task); err != nil {
-		return nil, fmt.Errorf("failed to validate task links: %w", err)
+	for _, link := range links {
+		if err := t.validator.Struct(link); err != nil {
+			return nil, fmt.Errorf(`failed to validate link "%s": %w: %w`, link.Link, err, ErrValidation)
+		}
 	}
 
 	if err := t.checkLinksExtension(links); err != nil {
 		return nil, fmt.Errorf("failed to check extensions: %w: %w", err, ErrValidation)
 	}
 
+	if err := t.checkLinksPolicy(links); err != nil {
+		return nil, fmt.Errorf("failed to check link policy: %w: %w", err, ErrValidation)
+	}
+
+	if err := checkLinksName(links); err != nil {
+		return nil, fmt.Errorf("failed to check names: %w: %w", err, ErrValidation)
+	}
+
+	// A full task is queued right away, so the slot is reserved before links are stored
+	// to not leave a full task that is never processed.
+	autoStart := t.autoStart && len(links)+len(task.FilesLink) == int(t.linksInFile)
+	activated := false
+	if autoStart {
+		if t.closed.Load() {
+			return nil, fmt.Errorf("service is shutting down: %w", ErrServiceBusy)
+		}
+
+		if activated, err = t.admission.Activate(taskID); err != nil {
+			return nil, err
+		}
+	}
+
 	for _, fileLink := range links {
 		fileLink.Status = models.NewTaskLinkStatus
 	}
-	task, err = t.taskRepo.AddLinksToTask(ctx, taskID, links)
+	// The task may be started or filled by a concurrent request since it was read,
+	// so the checks are repeated in the same update that stores the links.
+	task, err = t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if err := t.checkTaskAcceptsLinks(task, len(links)); err != nil {
+			return err
+		}
+
+		for _, fileLink := range links {
+			link := *fileLink
+			task.FilesLink = append(task.FilesLink, &link)
+		}
+
+		return nil
+	})
 	if err != nil {
+		if activated {
+			t.rollbackActivation(taskID, err)
+		}
+		if errors.Is(err, ErrTaskStarted) || errors.Is(err, ErrValidation) {
+			return nil, err
+		}
 		if errors.Is(err, storage.ErrTaskNotFound) {
 			return nil, ErrTaskNotFound
 		}
 
 		return nil, fmt.Errorf("failed to add links to task: %w", err)
 	}
+	t.admission.Touch(taskID)
 
-	if len(task.FilesLink) == int(t.linksInFile) {
-		_, ok := t.pool.TrySubmit(func() {
-			t.processTask(task)
-		})
-		if !ok {
-			return nil, ErrServiceBusy
+	if autoStart {
+		if task, err = t.submitTask(ctx, task.ID); err != nil {
+			// A task started by a concurrent request keeps the slot reserved here.
+			if activated && !errors.Is(err, ErrTaskStarted) {
+				t.admission.Deactivate(taskID)
+			}
+
+			return nil, err
+		}
+	}
+
+	log.Debug("operation completed")
+
+	return task, nil
+}
+
+func (t *TaskService) StartTask(ctx context.Context, taskID string) (*models.Task, error) {
+	const op = "taskService.StartTask"
+	log := t.log.With(slog.String("op", op))
+	log.Debug("start operation")
+
+	task, err := t.taskRepo.GetTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return nil, ErrTaskNotFound
 		}
+
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if len(task.FilesLink) == 0 {
+		return nil, fmt.Errorf("task has no links: %w", ErrValidation)
+	}
+
+	if task, err = t.submitTask(ctx, task.ID); err != nil {
+		return nil, err
 	}
 
 	log.Debug("operation completed")
@@ -176,85 +355,560 @@ func (t *TaskService) AddLinksToTask(ctx context.Context, taskID string, links [
 	return task, nil
 }
 
-func (t *TaskService) GetTaskResult(ctx context.Context, taskID string) (string, string, error) {
+// GetTaskResult returns the archive of the task, the archive is available only once the task is archived.
+// A format other than the task one converts the archive while it is read, an empty format keeps the task one.
+// With redirects enabled the stored archive is returned as a presigned link when the result store supports it.
+func (t *TaskService) GetTaskResult(ctx context.Context, taskID string, format models.ArchiveFormat) (*TaskResult, error) {
 	const op = "taskService.GetTaskResult"
 	log := t.log.With(slog.String("op", op))
 	log.Debug("start operation")
 
-	filePath := filepath.Join(t.archivesDir, taskID)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return "", "", ErrTaskNotFound
+	task, err := t.taskRepo.GetTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if task.Status != models.ArchivedTaskStatus {
+		return nil, ErrTaskNotFound
+	}
+
+	archiver := t.taskArchiver(task)
+	if format != "" && format != task.ArchiveFormat {
+		to, ok := t.archivers[format]
+		if !ok {
+			return nil, fmt.Errorf("archive format %q is not supported: %w", format, ErrValidation)
+		}
+
+		result, err := t.convertResult(ctx, task, archiver, to)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debug("operation completed")
+
+		return result, nil
+	}
+
+	result := &TaskResult{
+		Name:        taskID + archiver.Extension(),
+		MIMEType:    archiver.MIMEType(),
+		ModTime:     task.FinishedAt,
+		ArchiveInfo: ArchiveInfo{Size: task.ArchiveSize, SHA256: task.ArchiveSHA256},
+	}
+
+	if t.resultRedirect {
+		result.URL, err = t.presignResult(ctx, taskID, result.Name)
+		if err == nil {
+			log.Debug("operation completed")
+			return result, nil
+		}
+		if !errors.Is(err, storage.ErrPresignNotSupported) {
+			return nil, err
+		}
+	}
+
+	result.Content, err = t.results.Get(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, storage.ErrResultNotFound) {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get result: %w", err)
 	}
 
 	log.Debug("operation completed")
 
-	return filePath, taskID, nil
+	return result, nil
 }
 
-func (t *TaskService) processTask(task *models.Task) {
-	const op = "taskService.processTask"
+// CancelTask aborts task processing and removes its partial archive.
+func (t *TaskService) CancelTask(ctx context.Context, taskID string) (*models.Task, error) {
+	const op = "taskService.CancelTask"
+	log := t.log.With(slog.String("op", op), slog.String("task_id", taskID))
+	log.Debug("start operation")
+
+	var previous models.TaskStatus
+	task, err := t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if !canTransit(task.Status, models.CancelledTaskStatus) {
+			return fmt.Errorf("%s -> %s: %w", task.Status, models.CancelledTaskStatus, ErrInvalidTaskStatus)
+		}
+
+		previous = task.Status
+		t.setTaskStatus(task, models.CancelledTaskStatus)
+		for _, link := range task.FilesLink {
+			if link.Status == models.CompletedTaskLinkStatus {
+				continue
+			}
+
+			link.Status = models.ErrorTaskLinkStatus
+			link.FailureReason = models.CancelledFailureReason
+			link.Error = "task cancelled"
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, wrapRepoError(err)
+	}
+
+	if previous == models.QueuedTaskStatus {
+		if err := t.queue.Remove(ctx, taskID); err != nil {
+			log.Error("failed to remove task from queue", slog.String("error", err.Error()))
+		}
+	}
+
+	// Processing tasks release their slot when the worker finishes.
+	if previous != models.ProcessingTaskStatus {
+		t.admission.Release(taskID)
+	}
+
+	t.cancelRunning(taskID)
+	t.removeArchive(ctx, log, taskID)
+
+	log.Debug("operation completed")
+
+	return task, nil
+}
+
+// DeleteTask removes the task together with its archive, unfinished tasks are cancelled first.
+func (t *TaskService) DeleteTask(ctx context.Context, taskID string) error {
+	const op = "taskService.DeleteTask"
+	log := t.log.With(slog.String("op", op), slog.String("task_id", taskID))
+	log.Debug("start operation")
+
+	task, err := t.taskRepo.GetTask(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return ErrTaskNotFound
+		}
+
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if !task.IsFinished() {
+		if _, err := t.CancelTask(ctx, taskID); err != nil && !errors.Is(err, ErrInvalidTaskStatus) {
+			return fmt.Errorf("failed to cancel task: %w", err)
+		}
+	}
+
+	if err := t.taskRepo.DeleteTask(ctx, taskID); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return ErrTaskNotFound
+		}
+
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	t.removeArchive(ctx, log, taskID)
+
+	log.Debug("operation completed")
+
+	return nil
+}
+
+// Usage returns the current usage of task slots.
+func (t *TaskService) Usage() AdmissionUsage {
+	return t.admission.Usage()
+}
+
+// CancelAbandonedTasks cancels created tasks that were not started in time, releasing their slots.
+func (t *TaskService) CancelAbandonedTasks(ctx context.Context) {
+	if t.createdTimeout <= 0 {
+		return
+	}
+
+	for _, taskID := range t.admission.Abandoned(t.createdTimeout) {
+		if _, err := t.CancelTask(ctx, taskID); err != nil {
+			t.log.Error("failed to cancel abandoned task", slog.String("task_id", taskID), slog.String("error", err.Error()))
+			// A task that is gone or already finished has no use for the slot.
+			if errors.Is(err, ErrTaskNotFound) || errors.Is(err, ErrInvalidTaskStatus) {
+				t.admission.Release(taskID)
+			}
+
+			continue
+		}
+
+		t.log.Info("abandoned task cancelled", slog.String("task_id", taskID))
+	}
+}
+
+// Stop aborts downloads of the running tasks.
+func (t *TaskService) Stop() {
+	t.stop()
+}
+
+// Shutdown stops taking new tasks and waits for the running ones to finish. If ctx is done first,
+// the running tasks are aborted and queued again to be resumed on the next start.
+func (t *TaskService) Shutdown(ctx context.Context) error {
+	const op = "taskService.Shutdown"
 	log := t.log.With(slog.String("op", op))
-	log.Debug("start operation", slog.String("id", task.ID))
+	log.Debug("start operation")
 
-	t.pool.Go(func() {
-		defer t.taskInProcess.Add(-1)
-		if err := t.taskRepo.MarkTaskLinksInProcessStatus(context.TODO(), task.ID); err != nil {
-			t.log.Error("failed to update task status to in process", slog.String("error", err.Error()))
+	t.closed.Store(true)
+	t.stopWorkers()
 
-			if err := t.taskRepo.MarkTaskLinksCompleted(context.TODO(), task.ID, []string{}); err != nil {
-				t.log.Error("failed to update task status to error", slog.String("error", err.Error()))
+	done := make(chan struct{})
+	go func() {
+		t.workersWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Debug("operation completed")
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.stop()
+	<-done
+
+	return fmt.Errorf("running tasks aborted: %w", ctx.Err())
+}
+
+func (t *TaskService) submitTask(ctx context.Context, taskID string) (*models.Task, error) {
+	if t.closed.Load() {
+		return nil, fmt.Errorf("service is shutting down: %w", ErrServiceBusy)
+	}
+
+	activated, err := t.admission.Activate(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := t.transitTask(ctx, taskID, models.QueuedTaskStatus)
+	if err != nil {
+		if activated {
+			t.rollbackActivation(taskID, err)
+		}
+		if errors.Is(err, ErrInvalidTaskStatus) {
+			return nil, ErrTaskStarted
+		}
+
+		return nil, err
+	}
+
+	if err := t.queue.Push(ctx, taskID); err != nil {
+		// The task never reached the queue, so it is returned to the created state to be started again.
+		t.admission.Deactivate(taskID)
+		_, rollbackErr := t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+			task.Status = models.CreatedTaskStatus
+			return nil
+		})
+		if rollbackErr != nil {
+			t.log.Error("failed to rollback task status", slog.String("error", rollbackErr.Error()))
+		}
+
+		return nil, fmt.Errorf("failed to queue task: %w", err)
+	}
+
+	return t.withQueuePosition(ctx, task), nil
+}
+
+// rollbackActivation returns the active slot taken for a task that failed to start. A task that is no
+// longer created or no longer exists does not keep any slot, other tasks get their created slot back.
+func (t *TaskService) rollbackActivation(taskID string, err error) {
+	if errors.Is(err, ErrInvalidTaskStatus) || errors.Is(err, ErrTaskStarted) ||
+		errors.Is(err, ErrTaskNotFound) || errors.Is(err, storage.ErrTaskNotFound) {
+		t.admission.Release(taskID)
+		return
+	}
+
+	t.admission.Deactivate(taskID)
+}
+
+// runWorker processes queued tasks one by one until the service is stopped.
+func (t *TaskService) runWorker() {
+	defer t.workersWG.Done()
+
+	for t.workersCtx.Err() == nil {
+		taskID, err := t.queue.Pop(t.workersCtx)
+		if err != nil {
+			if t.workersCtx.Err() != nil {
+				return
 			}
 
-			return
+			t.log.Error("failed to pop task from queue", slog.String("error", err.Error()))
+			time.Sleep(time.Second)
+			continue
 		}
 
-		log := t.log.With(slog.String("task_id", task.ID))
-		linkContents := t.requester.GetLinksContents(log, getLinksFromTask(task))
+		t.processTask(taskID)
+	}
+}
+
+func (t *TaskService) withQueuePosition(ctx context.Context, task *models.Task) *models.Task {
+	if task.Status != models.QueuedTaskStatus {
+		return task
+	}
+
+	position, err := t.queue.Position(ctx, task.ID)
+	if err != nil {
+		t.log.Error("failed to get queue position", slog.String("task_id", task.ID), slog.String("error", err.Error()))
+		return task
+	}
+	task.QueuePosition = position
+
+	return task
+}
 
-		if err := t.archiver.ToArchive(task.ID, convertLinksFilename(linkContents)); err != nil {
-			t.log.Error("failed to archive task", slog.String("error", err.Error()))
-			if err := t.taskRepo.MarkTaskLinksCompleted(context.TODO(), task.ID, []string{}); err != nil {
-				t.log.Error("failed to update task status to error", slog.String("error", err.Error()))
+func (t *TaskService) processTask(taskID string) {
+	const op = "taskService.processTask"
+	log := t.log.With(slog.String("op", op), slog.String("task_id", taskID))
+	log.Debug("start operation")
+
+	defer t.admission.Release(taskID)
+
+	// Task state is stored even when processing is aborted, so only downloads use the cancellable context.
+	ctx := context.WithoutCancel(t.ctx)
+
+	taskCtx := t.setRunning(taskID)
+	defer t.cancelRunning(taskID)
+
+	// Links are marked in the same update as the task, so a concurrent cancel is not overwritten.
+	task, err := t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if !canTransit(task.Status, models.ProcessingTaskStatus) {
+			return fmt.Errorf("%s -> %s: %w", task.Status, models.ProcessingTaskStatus, ErrInvalidTaskStatus)
+		}
+
+		t.setTaskStatus(task, models.ProcessingTaskStatus)
+		for _, link := range task.FilesLink {
+			link.Status = models.InProcessTaskLinkStatus
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to update task status to processing", slog.String("error", err.Error()))
+		return
+	}
+
+	downloads := t.requester.GetLinksContents(taskCtx, log, getLinksFromTask(task))
+	defer RemoveDownloads(log, downloads)
+
+	if taskCtx.Err() != nil {
+		t.abortTask(ctx, log, taskID)
+		return
+	}
+
+	names := archiveFileNames(task.FilesLink, downloads)
+	archiveInfo, err := WriteArchive(taskCtx, t.taskArchiver(task), t.results, taskID, archiveFiles(names, downloads))
+	if err != nil && taskCtx.Err() != nil {
+		// The result store may give up on an upload aborted by the service stop.
+		t.abortTask(ctx, log, taskID)
+		return
+	}
+	if err != nil {
+		log.Error("failed to archive task", slog.String("error", err.Error()))
+		archiveErr := fmt.Errorf("failed to archive task: %w", err)
+		_, err = t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+			if task.Status == models.CancelledTaskStatus {
+				return fmt.Errorf("task cancelled: %w", ErrInvalidTaskStatus)
 			}
 
-			return
+			return failDownloads(task, downloads, archiveErr)
+		})
+		if err != nil {
+			log.Error("failed to update task links to error", slog.String("error", err.Error()))
 		}
+		t.failTask(ctx, taskID)
 
-		if err := t.taskRepo.MarkTaskLinksCompleted(context.TODO(), task.ID, getLinksFromMap(linkContents)); err != nil {
-			t.log.Error("failed to update task status to completed", slog.String("error", err.Error()))
-			return
+		return
+	}
+
+	_, err = t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if task.Status == models.CancelledTaskStatus {
+			return fmt.Errorf("task cancelled: %w", ErrInvalidTaskStatus)
 		}
 
-		log.Debug("operation completed")
+		task.ArchiveSize = archiveInfo.Size
+		task.ArchiveSHA256 = archiveInfo.SHA256
+
+		return applyDownloads(task, downloads, names)
 	})
+	if err != nil {
+		log.Error("failed to update task status to completed", slog.String("error", err.Error()))
+		if errors.Is(err, ErrInvalidTaskStatus) || errors.Is(err, storage.ErrTaskNotFound) {
+			t.removeArchive(ctx, log, taskID)
+			return
+		}
+
+		t.failTask(ctx, taskID)
+		return
+	}
+
+	if _, err := t.transitTask(ctx, taskID, models.ArchivedTaskStatus); err != nil {
+		log.Error("failed to update task status to archived", slog.String("error", err.Error()))
+		if errors.Is(err, ErrInvalidTaskStatus) || errors.Is(err, ErrTaskNotFound) {
+			t.removeArchive(ctx, log, taskID)
+		}
+
+		return
+	}
+
+	log.Debug("operation completed")
+}
+
+// abortTask handles a task whose processing context is done, tasks aborted by the service stop are
+// resumed on the next start and cancelled tasks are left as they are.
+func (t *TaskService) abortTask(ctx context.Context, log *slog.Logger, taskID string) {
+	if t.ctx.Err() != nil {
+		if err := t.requeueTask(ctx, taskID); err != nil {
+			log.Error("failed to requeue aborted task", slog.String("error", err.Error()))
+		}
+	}
+
+	log.Debug("task processing aborted")
+}
+
+// setRunning registers the running task and returns the context that is cancelled with the task.
+func (t *TaskService) setRunning(taskID string) context.Context {
+	t.runningMu.Lock()
+	defer t.runningMu.Unlock()
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	t.running[taskID] = cancel
+
+	return ctx
+}
+
+func (t *TaskService) cancelRunning(taskID string) {
+	t.runningMu.Lock()
+	defer t.runningMu.Unlock()
+
+	if cancel, ok := t.running[taskID]; ok {
+		cancel()
+		delete(t.running, taskID)
+	}
+}
+
+// convertResult streams the stored archive converted into the format of the to archiver.
+func (t *TaskService) convertResult(ctx context.Context, task *models.Task, from Archiver, to Archiver) (*TaskResult, error) {
+	info, err := t.results.Stat(ctx, task.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrResultNotFound) {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, fmt.Errorf("failed to stat result: %w", err)
+	}
+
+	reader, err := t.results.Get(ctx, task.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrResultNotFound) {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get result: %w", err)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer reader.Close()
+		pipeWriter.CloseWithError(convertArchive(pipeWriter, to, from, reader, info.Size))
+	}()
+
+	return &TaskResult{
+		Name:     task.ID + to.Extension(),
+		MIMEType: to.MIMEType(),
+		ModTime:  task.FinishedAt,
+		Content:  pipeReader,
+	}, nil
+}
+
+// taskArchiver returns the archiver of the task format, tasks created before formats were introduced use zip.
+func (t *TaskService) taskArchiver(task *models.Task) Archiver {
+	if archiver, ok := t.archivers[task.ArchiveFormat]; ok {
+		return archiver
+	}
+
+	return t.archivers[models.ZipArchiveFormat]
+}
+
+func (t *TaskService) presignResult(ctx context.Context, taskID string, fileName string) (string, error) {
+	if _, err := t.results.Stat(ctx, taskID); err != nil {
+		if errors.Is(err, storage.ErrResultNotFound) {
+			return "", ErrTaskNotFound
+		}
+
+		return "", fmt.Errorf("failed to stat result: %w", err)
+	}
+
+	link, err := t.results.Presign(ctx, taskID, fileName, t.presignTTL)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign result: %w", err)
+	}
+
+	return link, nil
+}
+
+func (t *TaskService) removeArchive(ctx context.Context, log *slog.Logger, taskID string) {
+	if err := t.results.Delete(ctx, taskID); err != nil {
+		log.Error("failed to remove archive", slog.String("error", err.Error()))
+	}
+}
+
+func (t *TaskService) failTask(ctx context.Context, taskID string) {
+	if _, err := t.transitTask(ctx, taskID, models.FailedTaskStatus); err != nil {
+		t.log.Error("failed to update task status to failed", slog.String("error", err.Error()))
+	}
+}
+
+// checkTaskAcceptsLinks returns an error if count more links can not be added to the task.
+func (t *TaskService) checkTaskAcceptsLinks(task *models.Task, count int) error {
+	if task.Status != models.CreatedTaskStatus {
+		return ErrTaskStarted
+	}
+
+	if count+len(task.FilesLink) > int(t.linksInFile) {
+		return fmt.Errorf("max tasks reached: %w", ErrValidation)
+	}
+
+	return nil
 }
 
 func (t *TaskService) checkLinksExtension(links []*models.FileLink) error {
 	for _, link := range links {
-		allowed := false
-		for _, extension := range t.allowedExtensions {
-			if strings.HasSuffix(link.Link, extension) {
-				allowed = true
-			}
+		linkURL, err := url.Parse(link.Link)
+		if err != nil {
+			return fmt.Errorf(`link "%s" is not valid url: %w`, link.Link, err)
 		}
-		if !allowed {
+
+		extension := strings.ToLower(strings.TrimPrefix(path.Ext(linkURL.Path), "."))
+		if !slices.Contains(t.allowedExtensions, extension) {
 			return fmt.Errorf(`link "%s" extension not allowed, allowed extensions %s`, link.Link, strings.Join(t.allowedExtensions, ","))
+		}
+	}
+	return nil
+}
 
+func (t *TaskService) checkLinksPolicy(links []*models.FileLink) error {
+	for _, link := range links {
+		linkURL, err := url.Parse(link.Link)
+		if err != nil {
+			return fmt.Errorf(`link "%s" is not valid url: %w`, link.Link, err)
+		}
+
+		if err := t.linkPolicy.Check(linkURL); err != nil {
+			return fmt.Errorf(`link "%s": %w`, link.Link, err)
 		}
 	}
+
 	return nil
 }
 
-func convertLinksFilename(linksInfo map[string][]byte) map[string][]byte {
-	result := make(map[string][]byte)
-	prefix := byte('1')
-	for link, data := range linksInfo {
-		name := string(prefix) + "_ " + url.PathEscape(link)
-		result[name] = data
-		prefix++
+// checkLinksName rejects names given with links that are not plain file names.
+func checkLinksName(links []*models.FileLink) error {
+	for _, link := range links {
+		if link.Name != "" && sanitizeFileName(link.Name) != link.Name {
+			return fmt.Errorf(`link "%s" name "%s" is not a valid file name`, link.Link, link.Name)
+		}
 	}
 
-	return result
+	return nil
 }
 
 func getLinksFromTask(task *models.Task) []string {
@@ -266,11 +920,56 @@ func getLinksFromTask(task *models.Task) []string {
 	return links
 }
 
-func getLinksFromMap(linksInfo map[string][]byte) []string {
-	links := make([]string, 0, len(linksInfo))
-	for key := range linksInfo {
-		links = append(links, key)
+// failDownloads stores download results on the task links like applyDownloads when the files can not be
+// archived, links downloaded successfully fail with the internal reason and err.
+func failDownloads(task *models.Task, downloads []*DownloadResult, err error) error {
+	if err := applyDownloads(task, downloads, make([]string, len(downloads))); err != nil {
+		return err
 	}
 
-	return links
+	for _, link := range task.FilesLink {
+		if link.Status != models.CompletedTaskLinkStatus {
+			continue
+		}
+
+		link.Status = models.ErrorTaskLinkStatus
+		link.FailureReason = models.InternalFailureReason
+		link.Error = err.Error()
+	}
+
+	return nil
+}
+
+// applyDownloads stores download results and archive file names on the task links, downloads are expected in the links order.
+func applyDownloads(task *models.Task, downloads []*DownloadResult, names []string) error {
+	if len(task.FilesLink) != len(downloads) {
+		return fmt.Errorf("task has %d links, got %d downloads", len(task.FilesLink), len(downloads))
+	}
+
+	for idx, download := range downloads {
+		link := task.FilesLink[idx]
+		link.HTTPStatus = download.StatusCode
+		link.ContentType = download.ContentType
+		link.BytesReceived = download.Size
+		link.Attempts = download.Attempts
+		link.ArchiveName = names[idx]
+		link.FinalURL = download.FinalURL
+		link.Redirects = download.Redirects
+
+		if download.Err != nil {
+			link.Status = models.ErrorTaskLinkStatus
+			link.FailureReason = downloadFailureReason(download.Err)
+			link.Error = download.Err.Error()
+
+			var downloadErr *DownloadError
+			if errors.As(download.Err, &downloadErr) {
+				link.SizeLimit = downloadErr.Limit
+			}
+			continue
+		}
+
+		link.Status = models.CompletedTaskLinkStatus
+	}
+
+	return nil
 }
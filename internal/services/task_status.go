@@ -0,0 +1,57 @@
+package services
+
+import (
+	"270725/internal/models"
+	"270725/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var taskTransitions = map[models.TaskStatus][]models.TaskStatus{
+	models.CreatedTaskStatus:    {models.QueuedTaskStatus, models.CancelledTaskStatus},
+	models.QueuedTaskStatus:     {models.ProcessingTaskStatus, models.FailedTaskStatus, models.CancelledTaskStatus},
+	models.ProcessingTaskStatus: {models.ArchivedTaskStatus, models.FailedTaskStatus, models.CancelledTaskStatus},
+}
+
+func canTransit(from, to models.TaskStatus) bool {
+	return slices.Contains(taskTransitions[from], to)
+}
+
+// transitTask atomically moves the task to the given status if the transition is allowed.
+func (t *TaskService) transitTask(ctx context.Context, taskID string, to models.TaskStatus) (*models.Task, error) {
+	task, err := t.taskRepo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		if !canTransit(task.Status, to) {
+			return fmt.Errorf("%s -> %s: %w", task.Status, to, ErrInvalidTaskStatus)
+		}
+
+		t.setTaskStatus(task, to)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapRepoError(err)
+	}
+
+	return task, nil
+}
+
+// setTaskStatus changes the task status, finished tasks get the expiry time after which the janitor removes them.
+func (t *TaskService) setTaskStatus(task *models.Task, status models.TaskStatus) {
+	task.SetStatus(status, time.Now().UTC())
+	if task.IsFinished() && t.archiveTTL > 0 {
+		task.ExpiresAt = task.FinishedAt.Add(t.archiveTTL)
+	}
+}
+
+func wrapRepoError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidTaskStatus):
+		return err
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return ErrTaskNotFound
+	default:
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+}
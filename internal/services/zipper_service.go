@@ -2,47 +2,113 @@ package services
 
 import (
 	"archive/zip"
-	"fmt"
-	"os"
-	"path/filepath"
+	"compress/flate"
+	"io"
+	"path"
+	"slices"
+	"strings"
 )
 
+// Zipper writes zip archives. Files with an already compressed type are stored as is, deflating them
+// costs CPU without making the archive noticeably smaller, other files are deflated with the level.
 type Zipper struct {
-	archivePath string
+	level            int
+	storedExtensions []string
 }
 
-func NewZipper(archivePath string) (*Zipper, error) {
-	if err := os.MkdirAll(archivePath, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to create zipper directory: %w", err)
+// NewZipper returns a zipper deflating with the level, 0 stores all files. Extensions are matched
+// case-insensitively and without the leading dot.
+func NewZipper(level int, storedExtensions []string) *Zipper {
+	extensions := make([]string, 0, len(storedExtensions))
+	for _, extension := range storedExtensions {
+		extensions = append(extensions, strings.ToLower(strings.TrimPrefix(extension, ".")))
 	}
 
 	return &Zipper{
-		archivePath: archivePath,
-	}, nil
+		level:            level,
+		storedExtensions: extensions,
+	}
 }
 
-func (z *Zipper) ToArchive(archiveName string, files map[string][]byte) error {
-	archiveName = filepath.Join(filepath.Base(z.archivePath), archiveName)
+func (z *Zipper) MIMEType() string {
+	return "application/zip"
+}
 
-	archive, err := os.Create(archiveName)
-	if err != nil {
-		return fmt.Errorf("failed to create zipper file: %w", err)
+func (z *Zipper) Extension() string {
+	return ".zip"
+}
+
+func (z *Zipper) NewWriter(w io.Writer) ArchiveWriter {
+	writer := zip.NewWriter(w)
+	writer.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, z.level)
+	})
+
+	return &zipWriter{writer: writer, zipper: z}
+}
+
+// method returns the compression method of the file.
+func (z *Zipper) method(name string) uint16 {
+	if z.level == flate.NoCompression {
+		return zip.Store
+	}
+
+	extension := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if slices.Contains(z.storedExtensions, extension) {
+		return zip.Store
 	}
-	defer archive.Close()
 
-	zipWriter := zip.NewWriter(archive)
-	defer zipWriter.Close()
+	return zip.Deflate
+}
+
+func (z *Zipper) Walk(r io.ReaderAt, size int64, fn func(name string, size int64, r io.Reader) error) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
 
-	for filename, data := range files {
-		w, err := zipWriter.Create(filename)
-		if err != nil {
-			return fmt.Errorf("failed to create zipWriter %w", err)
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
 		}
 
-		if _, err := w.Write(data); err != nil {
-			return fmt.Errorf("failed to write zipWriter %w", err)
+		if err := walkZipFile(file, fn); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// walkZipFile passes the file content to fn, the zip reader checks the file CRC when it reaches the end of the file.
+func walkZipFile(file *zip.File, fn func(name string, size int64, r io.Reader) error) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return fn(file.Name, int64(file.UncompressedSize64), r)
+}
+
+type zipWriter struct {
+	writer *zip.Writer
+	zipper *Zipper
+}
+
+func (w *zipWriter) Add(name string, _ int64, r io.Reader) error {
+	fileWriter, err := w.writer.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: w.zipper.method(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fileWriter, r)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,216 @@
+package boltdb
+
+import (
+	"270725/internal/models"
+	"270725/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var tasksBucket = []byte("tasks")
+
+type Bolt struct {
+	db *bolt.DB
+}
+
+func NewBolt(path string) (*Bolt, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create tasks bucket: %w", err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+func (b *Bolt) NewTask(_ context.Context) (string, error) {
+	task := &models.Task{
+		ID:        uuid.NewString(),
+		Status:    models.CreatedTaskStatus,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save task: %w", err)
+	}
+
+	return task.ID, nil
+}
+
+func (b *Bolt) GetAllTasks(_ context.Context) ([]*models.Task, error) {
+	tasks := make([]*models.Task, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, value []byte) error {
+			task := &models.Task{}
+			if err := json.Unmarshal(value, task); err != nil {
+				return fmt.Errorf("failed to decode task: %w", err)
+			}
+
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+func (b *Bolt) GetTask(_ context.Context, id string) (*models.Task, error) {
+	var task *models.Task
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = getTask(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *Bolt) AddLinksToTask(_ context.Context, taskID string, links []*models.FileLink) (*models.Task, error) {
+	var task *models.Task
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		task, err = getTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		for _, fileLink := range links {
+			link := *fileLink
+			task.FilesLink = append(task.FilesLink, &link)
+		}
+
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *Bolt) UpdateTask(_ context.Context, taskID string, update func(task *models.Task) error) (*models.Task, error) {
+	var task *models.Task
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		task, err = getTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := update(task); err != nil {
+			return err
+		}
+
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *Bolt) DeleteTask(_ context.Context, taskID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tasksBucket)
+		if bucket.Get([]byte(taskID)) == nil {
+			return storage.ErrTaskNotFound
+		}
+
+		return bucket.Delete([]byte(taskID))
+	})
+}
+
+func (b *Bolt) MarkTaskLinksInProcessStatus(_ context.Context, taskID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		task, err := getTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		for idx := range task.FilesLink {
+			task.FilesLink[idx].Status = models.InProcessTaskLinkStatus
+		}
+
+		return putTask(tx, task)
+	})
+}
+
+func (b *Bolt) MarkTaskLinksCompleted(_ context.Context, taskID string, completedLinks []string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		task, err := getTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		for idx := range task.FilesLink {
+			if slices.Contains(completedLinks, task.FilesLink[idx].Link) {
+				task.FilesLink[idx].Status = models.CompletedTaskLinkStatus
+				continue
+			}
+
+			task.FilesLink[idx].Status = models.ErrorTaskLinkStatus
+		}
+
+		return putTask(tx, task)
+	})
+}
+
+func getTask(tx *bolt.Tx, id string) (*models.Task, error) {
+	value := tx.Bucket(tasksBucket).Get([]byte(id))
+	if value == nil {
+		return nil, storage.ErrTaskNotFound
+	}
+
+	task := &models.Task{}
+	if err := json.Unmarshal(value, task); err != nil {
+		return nil, fmt.Errorf("failed to decode task: %w", err)
+	}
+
+	return task, nil
+}
+
+func putTask(tx *bolt.Tx, task *models.Task) error {
+	value, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode task: %w", err)
+	}
+
+	if err := tx.Bucket(tasksBucket).Put([]byte(task.ID), value); err != nil {
+		return fmt.Errorf("failed to put task: %w", err)
+	}
+
+	return nil
+}
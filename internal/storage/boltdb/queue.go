@@ -0,0 +1,134 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+var queueBucket = []byte("queue")
+
+// Queue is a FIFO queue of task ids persisted in the same database as tasks, so queued tasks survive restarts.
+type Queue struct {
+	db     *bolt.DB
+	notify chan struct{}
+}
+
+func NewQueue(storage *Bolt) (*Queue, error) {
+	err := storage.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(queueBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue bucket: %w", err)
+	}
+
+	return &Queue{
+		db:     storage.db,
+		notify: make(chan struct{}, 1),
+	}, nil
+}
+
+func (q *Queue) Push(_ context.Context, taskID string) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(queueBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(binary.BigEndian.AppendUint64(nil, seq), []byte(taskID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push task: %w", err)
+	}
+
+	q.signal()
+
+	return nil
+}
+
+// Pop removes the first task id from the queue, blocking until one is available or ctx is done.
+func (q *Queue) Pop(ctx context.Context) (string, error) {
+	for {
+		var taskID string
+		var remaining bool
+		err := q.db.Update(func(tx *bolt.Tx) error {
+			cursor := tx.Bucket(queueBucket).Cursor()
+			key, value := cursor.First()
+			if key == nil {
+				return nil
+			}
+
+			taskID = string(value)
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+
+			next, _ := cursor.First()
+			remaining = next != nil
+
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to pop task: %w", err)
+		}
+
+		if taskID != "" {
+			if remaining {
+				q.signal()
+			}
+
+			return taskID, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *Queue) Remove(_ context.Context, taskID string) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(queueBucket).Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if bytes.Equal(value, []byte(taskID)) {
+				return cursor.Delete()
+			}
+		}
+
+		return nil
+	})
+}
+
+// Position returns 1-based position of the task in the queue, 0 means the task is not queued.
+func (q *Queue) Position(_ context.Context, taskID string) (int, error) {
+	position := 0
+	err := q.db.View(func(tx *bolt.Tx) error {
+		idx := 0
+		return tx.Bucket(queueBucket).ForEach(func(_, value []byte) error {
+			idx++
+			if position == 0 && bytes.Equal(value, []byte(taskID)) {
+				position = idx
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	return position, nil
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
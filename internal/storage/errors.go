@@ -3,5 +3,7 @@ package storage
 import "errors"
 
 var (
-	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskNotFound        = errors.New("task not found")
+	ErrResultNotFound      = errors.New("result not found")
+	ErrPresignNotSupported = errors.New("presigned urls are not supported")
 )
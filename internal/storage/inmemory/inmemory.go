@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"slices"
 	"sync"
+	"time"
 )
 
 type Memory struct {
@@ -26,7 +27,9 @@ func (m *Memory) NewTask(_ context.Context) (string, error) {
 	defer m.mu.Unlock()
 
 	task := &models.Task{
-		ID: uuid.NewString(),
+		ID:        uuid.NewString(),
+		Status:    models.CreatedTaskStatus,
+		CreatedAt: time.Now().UTC(),
 	}
 	m.tasks[task.ID] = task
 
@@ -39,7 +42,7 @@ func (m *Memory) GetAllTasks(_ context.Context) ([]*models.Task, error) {
 
 	tasks := make([]*models.Task, 0, len(m.tasks))
 	for _, task := range m.tasks {
-		tasks = append(tasks, task)
+		tasks = append(tasks, task.Clone())
 	}
 
 	return tasks, nil
@@ -54,7 +57,7 @@ func (m *Memory) GetTask(_ context.Context, id string) (*models.Task, error) {
 		return nil, storage.ErrTaskNotFound
 	}
 
-	return task, nil
+	return task.Clone(), nil
 }
 
 func (m *Memory) AddLinksToTask(_ context.Context, taskID string, links []*models.FileLink) (*models.Task, error) {
@@ -67,11 +70,42 @@ func (m *Memory) AddLinksToTask(_ context.Context, taskID string, links []*model
 	}
 
 	for _, fileLink := range links {
-		task.FilesLink = append(task.FilesLink, fileLink)
+		link := *fileLink
+		task.FilesLink = append(task.FilesLink, &link)
 	}
 	m.tasks[taskID] = task
 
-	return task, nil
+	return task.Clone(), nil
+}
+
+func (m *Memory) UpdateTask(_ context.Context, taskID string, update func(task *models.Task) error) (*models.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return nil, storage.ErrTaskNotFound
+	}
+
+	task = task.Clone()
+	if err := update(task); err != nil {
+		return nil, err
+	}
+	m.tasks[taskID] = task
+
+	return task.Clone(), nil
+}
+
+func (m *Memory) DeleteTask(_ context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tasks[taskID]; !exists {
+		return storage.ErrTaskNotFound
+	}
+	delete(m.tasks, taskID)
+
+	return nil
 }
 
 func (m *Memory) MarkTaskLinksInProcessStatus(_ context.Context, taskID string) error {
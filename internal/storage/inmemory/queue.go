@@ -0,0 +1,82 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Queue is a FIFO queue of task ids kept in memory.
+type Queue struct {
+	mu     sync.Mutex
+	ids    []string
+	notify chan struct{}
+}
+
+func NewQueue() *Queue {
+	return &Queue{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) Push(_ context.Context, taskID string) error {
+	q.mu.Lock()
+	q.ids = append(q.ids, taskID)
+	q.mu.Unlock()
+
+	q.signal()
+
+	return nil
+}
+
+// Pop removes the first task id from the queue, blocking until one is available or ctx is done.
+func (q *Queue) Pop(ctx context.Context) (string, error) {
+	for {
+		q.mu.Lock()
+		if len(q.ids) > 0 {
+			taskID := q.ids[0]
+			q.ids = q.ids[1:]
+			remaining := len(q.ids)
+			q.mu.Unlock()
+
+			if remaining > 0 {
+				q.signal()
+			}
+
+			return taskID, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *Queue) Remove(_ context.Context, taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.ids = slices.DeleteFunc(q.ids, func(id string) bool {
+		return id == taskID
+	})
+
+	return nil
+}
+
+// Position returns 1-based position of the task in the queue, 0 means the task is not queued.
+func (q *Queue) Position(_ context.Context, taskID string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return slices.Index(q.ids, taskID) + 1, nil
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,140 @@
+package localfs
+
+import (
+	"270725/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// tempResultPattern matches results that are still being copied into the directory.
+const tempResultPattern = "*.tmp"
+
+// Local keeps results as files in a directory. It is the only place that maps result names
+// to file paths, so the archiver writing results and the service reading them always agree.
+type Local struct {
+	path string
+}
+
+// NewLocal creates the directory if needed. A relative path is resolved against the current
+// working directory once, so later changes of it do not move results.
+func NewLocal(path string) (*Local, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve results directory: %w", err)
+	}
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	// Results left unfinished by a crash are never renamed to their final path, so they are removed on start.
+	tempResults, err := filepath.Glob(filepath.Join(path, tempResultPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unfinished results: %w", err)
+	}
+	for _, tempResult := range tempResults {
+		if err := os.Remove(tempResult); err != nil {
+			return nil, fmt.Errorf("failed to remove unfinished result: %w", err)
+		}
+	}
+
+	return &Local{path: path}, nil
+}
+
+// Put moves the file at path into the directory. Files from another file system are copied
+// into a temporary file first, so a partially copied result is never visible under name.
+func (l *Local) Put(_ context.Context, name string, path string) error {
+	err := os.Rename(path, l.resultPath(name))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("failed to move result: %w", err)
+	}
+
+	return l.copy(name, path)
+}
+
+func (l *Local) Get(_ context.Context, name string) (storage.ResultReader, error) {
+	file, err := os.Open(l.resultPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, storage.ErrResultNotFound
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (l *Local) Stat(_ context.Context, name string) (storage.ResultInfo, error) {
+	info, err := os.Stat(l.resultPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return storage.ResultInfo{}, storage.ErrResultNotFound
+		}
+
+		return storage.ResultInfo{}, err
+	}
+
+	return storage.ResultInfo{Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete removes the result, a missing result is not an error.
+func (l *Local) Delete(_ context.Context, name string) error {
+	if err := os.Remove(l.resultPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Presign is not supported, local results are streamed by the service.
+func (l *Local) Presign(context.Context, string, string, time.Duration) (string, error) {
+	return "", storage.ErrPresignNotSupported
+}
+
+func (l *Local) resultPath(name string) string {
+	return filepath.Join(l.path, filepath.Base(name))
+}
+
+func (l *Local) copy(name string, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(l.path, filepath.Base(name)+"-"+tempResultPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(dst.Name())
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to copy result: %w", err)
+	}
+
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to sync result: %w", err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close result: %w", err)
+	}
+
+	if err := os.Rename(dst.Name(), l.resultPath(name)); err != nil {
+		return fmt.Errorf("failed to move result: %w", err)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"io"
+	"time"
+)
+
+// ResultInfo describes a stored task result.
+type ResultInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// ResultReader reads a stored result, results are read both sequentially and at random offsets.
+type ResultReader interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+}
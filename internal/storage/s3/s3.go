@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"270725/internal/config"
+	"270725/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"mime"
+	"net/url"
+	"time"
+)
+
+// S3 keeps results as objects in a bucket of an S3-compatible storage.
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3 connects to the storage and creates the bucket if it does not exist.
+func NewS3(ctx context.Context, cfg config.S3Config) (*S3, error) {
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket: %w", err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+
+	return &S3{client: client, bucket: cfg.S3Bucket}, nil
+}
+
+// Put uploads the file at path, the file itself is left in place.
+func (s *S3) Put(ctx context.Context, name string, path string) error {
+	if _, err := s.client.FPutObject(ctx, s.bucket, name, path, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to upload result: %w", err)
+	}
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, name string) (storage.ResultReader, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	// GetObject is lazy, the object is requested to report a missing result right away.
+	if _, err := object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, wrapError(err)
+	}
+
+	return object, nil
+}
+
+func (s *S3) Stat(ctx context.Context, name string) (storage.ResultInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return storage.ResultInfo{}, wrapError(err)
+	}
+
+	return storage.ResultInfo{Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+// Delete removes the result, a missing result is not an error.
+func (s *S3) Delete(ctx context.Context, name string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{}); err != nil {
+		if errors.Is(wrapError(err), storage.ErrResultNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to delete result: %w", err)
+	}
+
+	return nil
+}
+
+// Presign returns a temporary link to download the result as fileName.
+func (s *S3) Presign(ctx context.Context, name string, fileName string, expires time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+
+	link, err := s.client.PresignedGetObject(ctx, s.bucket, name, expires, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign result: %w", err)
+	}
+
+	return link.String(), nil
+}
+
+func wrapError(err error) error {
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return storage.ErrResultNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,302 @@
+package tests
+
+import (
+	"270725/internal/models"
+	v1 "270725/internal/rest/v1"
+	bp "270725/internal/rest/v1/boileplate"
+	"270725/internal/services"
+	"270725/internal/storage/inmemory"
+	"270725/internal/storage/localfs"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteArchiveAtomically(t *testing.T) {
+	for format, archiver := range services.NewArchivers(newTestConfig()) {
+		t.Run(string(format), func(t *testing.T) {
+			ctx := context.Background()
+			t.Chdir(t.TempDir())
+			tempDir := t.TempDir()
+			t.Setenv("TMPDIR", tempDir)
+
+			require.NoError(t, os.WriteFile("a.pdf", []byte(pdfHeader+"a"), 0o644))
+			require.NoError(t, os.WriteFile("b.pdf", []byte(pdfHeader+"b"), 0o644))
+
+			results, err := localfs.NewLocal("archives")
+			require.NoError(t, err)
+
+			info, err := services.WriteArchive(ctx, archiver, results, "task", []services.ArchiveFile{
+				{Name: "a.pdf", Path: "a.pdf"},
+				{Name: "b.pdf", Path: "b.pdf"},
+			})
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join("archives", "task"))
+			require.NoError(t, err)
+			checksum := sha256.Sum256(content)
+			require.Equal(t, hex.EncodeToString(checksum[:]), info.SHA256)
+			require.Equal(t, int64(len(content)), info.Size)
+			require.Equal(t, map[string]string{"a.pdf": pdfHeader + "a", "b.pdf": pdfHeader + "b"}, readArchive(t, archiver, content))
+
+			// A failed write leaves neither the final archive nor the temporary file behind.
+			_, err = services.WriteArchive(ctx, archiver, results, "broken", []services.ArchiveFile{
+				{Name: "a.pdf", Path: "a.pdf"},
+				{Name: "missing.pdf", Path: "missing.pdf"},
+			})
+			require.Error(t, err)
+
+			entries, err := os.ReadDir("archives")
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			require.Equal(t, "task", entries[0].Name())
+
+			entries, err = os.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestZipperCompressionPolicy(t *testing.T) {
+	files := map[string]string{
+		"photo.JPG":  strings.Repeat("a", 4096),
+		"report.pdf": strings.Repeat("b", 4096),
+		"notes.txt":  strings.Repeat("c", 4096),
+	}
+
+	policies := map[string]struct {
+		zipper  *services.Zipper
+		methods map[string]uint16
+	}{
+		"store compressed types": {
+			zipper:  services.NewZipper(6, []string{"jpg", ".pdf"}),
+			methods: map[string]uint16{"photo.JPG": zip.Store, "report.pdf": zip.Store, "notes.txt": zip.Deflate},
+		},
+		"level zero stores all": {
+			zipper:  services.NewZipper(0, nil),
+			methods: map[string]uint16{"photo.JPG": zip.Store, "report.pdf": zip.Store, "notes.txt": zip.Store},
+		},
+	}
+
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := policy.zipper.NewWriter(&buf)
+			for name, content := range files {
+				require.NoError(t, writer.Add(name, int64(len(content)), strings.NewReader(content)))
+			}
+			require.NoError(t, writer.Close())
+
+			archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			require.Len(t, archive.File, len(files))
+			for _, file := range archive.File {
+				require.Equal(t, policy.methods[file.Name], file.Method, file.Name)
+			}
+
+			require.Equal(t, files, readArchive(t, policy.zipper, buf.Bytes()))
+		})
+	}
+}
+
+// BenchmarkZipperCompression compares deflating every file with storing already compressed ones.
+// Random bytes stand for a JPG, they do not compress just like real compressed images.
+func BenchmarkZipperCompression(b *testing.B) {
+	const fileSize = 4 << 20
+
+	compressed := make([]byte, fileSize)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(compressed)
+	text := bytes.Repeat([]byte("lorem ipsum dolor sit amet, consectetur adipiscing elit\n"), fileSize/56)
+
+	policies := map[string]*services.Zipper{
+		"deflate all":      services.NewZipper(6, nil),
+		"store compressed": services.NewZipper(6, []string{"jpg"}),
+		"store all":        services.NewZipper(0, nil),
+	}
+
+	for name, zipper := range policies {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(compressed) + len(text)))
+
+			var size int
+			for b.Loop() {
+				var buf bytes.Buffer
+				writer := zipper.NewWriter(&buf)
+				require.NoError(b, writer.Add("photo.jpg", int64(len(compressed)), bytes.NewReader(compressed)))
+				require.NoError(b, writer.Add("notes.txt", int64(len(text)), bytes.NewReader(text)))
+				require.NoError(b, writer.Close())
+				size = buf.Len()
+			}
+
+			b.ReportMetric(float64(size)/(1<<20), "archive-MB")
+		})
+	}
+}
+
+func TestTaskArchiveFormats(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.ArchiveFormat = string(models.TarGzArchiveFormat)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+	archivers := services.NewArchivers(cfg)
+
+	_, err := service.NewTask(ctx, "rar")
+	require.ErrorIs(t, err, services.ErrValidation)
+
+	for _, format := range []models.ArchiveFormat{"", models.ZipArchiveFormat, models.TarArchiveFormat, models.TarZstArchiveFormat} {
+		expected := format
+		if expected == "" {
+			expected = models.TarGzArchiveFormat
+		}
+
+		t.Run(string(expected), func(t *testing.T) {
+			taskID := runFormatTask(t, service, format, server.URL+"/a.pdf")
+			task, err := service.GetTask(ctx, taskID)
+			require.NoError(t, err)
+			require.Equal(t, expected, task.ArchiveFormat)
+
+			result, err := service.GetTaskResult(ctx, taskID, "")
+			require.NoError(t, err)
+			defer result.Content.Close()
+			require.Equal(t, taskID+archivers[expected].Extension(), result.Name)
+			require.Equal(t, archivers[expected].MIMEType(), result.MIMEType)
+
+			content, err := io.ReadAll(result.Content)
+			require.NoError(t, err)
+			require.Len(t, readArchive(t, archivers[expected], content), 1)
+		})
+	}
+}
+
+func TestGetResultConvertsArchive(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+	h := v1.NewHandler(setupTestLogger(), service)
+
+	c, res := createResponser(http.MethodPost, urlPrefix+"/task", `{"format":"zip"}`)
+	require.NoError(t, h.AddTask(c))
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	task := bp.Task{}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &task))
+	require.Equal(t, bp.ArchiveFormatZip, *task.Format)
+
+	c, _ = createResponser(http.MethodPost, urlPrefix+"/task", `{"format":"rar"}`)
+	require.ErrorIs(t, h.AddTask(c), services.ErrValidation)
+
+	taskID := task.Id
+	_, err := service.AddLinksToTask(context.Background(), taskID, []*models.FileLink{
+		{Link: server.URL + "/a.pdf"},
+		{Link: server.URL + "/b.pdf"},
+	})
+	require.NoError(t, err)
+	_, err = service.StartTask(context.Background(), taskID)
+	require.NoError(t, err)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+
+	c, res = createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result?format=zip", "")
+	require.NoError(t, h.GetResult(c, taskID, bp.GetResultParams{}))
+	original := readArchive(t, services.NewZipper(0, nil), res.Body.Bytes())
+	require.Len(t, original, 2)
+
+	format := bp.ArchiveFormatTarZst
+	c, res = createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result?format=tar.zst", "")
+	require.NoError(t, h.GetResult(c, taskID, bp.GetResultParams{Format: &format}))
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "application/zstd", res.Header().Get(echo.HeaderContentType))
+	require.Equal(t, fmt.Sprintf("attachment; filename=%q", taskID+".tar.zst"), res.Header().Get(echo.HeaderContentDisposition))
+	require.Empty(t, res.Header().Get("ETag"))
+	require.Equal(t, original, readArchive(t, services.NewTarrer(services.TarCompressionZstd), res.Body.Bytes()))
+}
+
+// readArchive returns the content of every file in the archive by its name.
+func readArchive(t *testing.T, archiver services.Archiver, archive []byte) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := archiver.Walk(bytes.NewReader(archive), int64(len(archive)), func(name string, size int64, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, size, int64(len(content)))
+		files[name] = string(content)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
+
+func TestLocalResultStoreLayouts(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	layouts := map[string]func(t *testing.T) (archivesDir string, realDir string){
+		"relative": func(t *testing.T) (string, string) {
+			return "archives", filepath.Join(currentDir(t), "archives")
+		},
+		"absolute": func(t *testing.T) (string, string) {
+			dir := filepath.Join(t.TempDir(), "archives")
+			return dir, dir
+		},
+		"nested": func(t *testing.T) (string, string) {
+			return filepath.Join("data", "tasks", "archives"), filepath.Join(currentDir(t), "data", "tasks", "archives")
+		},
+		"symlinked": func(t *testing.T) (string, string) {
+			target := t.TempDir()
+			require.NoError(t, os.Symlink(target, "archives"))
+			return "archives", target
+		},
+	}
+
+	for name, layout := range layouts {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := newServiceConfig(t)
+			var realDir string
+			cfg.ArchivesDir, realDir = layout(t)
+
+			service := newTaskService(t, cfg, inmemory.NewMemory())
+			taskID := runTask(t, service, server.URL+"/a.pdf")
+
+			result, err := service.GetTaskResult(ctx, taskID, "")
+			require.NoError(t, err)
+			require.NoError(t, result.Content.Close())
+			require.FileExists(t, filepath.Join(realDir, taskID))
+
+			require.NoError(t, service.DeleteTask(ctx, taskID))
+			require.NoFileExists(t, filepath.Join(realDir, taskID))
+		})
+	}
+}
+
+func currentDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	require.NoError(t, err)
+
+	return dir
+}
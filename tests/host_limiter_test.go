@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"270725/internal/config"
+	"270725/internal/services"
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequesterLimitsHostConnections(t *testing.T) {
+	first, firstPeak := newConcurrencyServer(50 * time.Millisecond)
+	defer first.Close()
+	second, secondPeak := newConcurrencyServer(50 * time.Millisecond)
+	defer second.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newHostLimitsConfig()
+	cfg.HostMaxConnections = 2
+	cfg.HostLimits = []string{"LOCALHOST=4"}
+
+	// The second server is reached by the host name with its own limit.
+	links := make([]string, 0, 12)
+	for i := range 6 {
+		links = append(links, first.URL+"/"+strconv.Itoa(i)+".pdf", localhostURL(t, second)+"/"+strconv.Itoa(i)+".pdf")
+	}
+
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, links)
+	defer services.RemoveDownloads(logger, downloads)
+
+	for _, download := range downloads {
+		require.NoError(t, download.Err)
+	}
+	require.Equal(t, int32(2), firstPeak.Load())
+	require.Equal(t, int32(4), secondPeak.Load())
+}
+
+func TestRequesterRateLimitsHosts(t *testing.T) {
+	var mu sync.Mutex
+	arrivals := make(map[string][]time.Time)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals[r.Host] = append(arrivals[r.Host], time.Now())
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newHostLimitsConfig()
+	cfg.HostRateLimit = 20
+	cfg.HostLimits = []string{"localhost=0:0"}
+
+	links := make([]string, 0, 10)
+	for i := range 5 {
+		links = append(links, server.URL+"/"+strconv.Itoa(i)+".pdf", localhostURL(t, server)+"/"+strconv.Itoa(i)+".pdf")
+	}
+
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, links)
+	defer services.RemoveDownloads(logger, downloads)
+
+	for _, download := range downloads {
+		require.NoError(t, download.Err)
+	}
+
+	span := func(host string) time.Duration {
+		times := arrivals[host]
+		require.Len(t, times, 5)
+		return times[len(times)-1].Sub(times[0])
+	}
+
+	// 5 requests at 20 per second with a burst of 1 take at least 4 intervals of 50ms.
+	require.GreaterOrEqual(t, span(strings.TrimPrefix(server.URL, "http://")), 150*time.Millisecond)
+	require.Less(t, span(strings.TrimPrefix(localhostURL(t, server), "http://")), 100*time.Millisecond)
+}
+
+func TestRequesterSchedulesHostsFairly(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var startOnce sync.Once
+
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, strings.Split(r.URL.Path, "/")[1])
+		mu.Unlock()
+
+		startOnce.Do(func() { close(started) })
+		<-release
+
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newHostLimitsConfig()
+	cfg.HostMaxConnections = 1
+	requester := newRequester(t, cfg)
+
+	taskLinks := func(task string) []string {
+		links := make([]string, 0, 4)
+		for i := range 4 {
+			links = append(links, server.URL+"/"+task+"/"+strconv.Itoa(i)+".pdf")
+		}
+		return links
+	}
+
+	var wg sync.WaitGroup
+	download := func(task string) {
+		defer wg.Done()
+		downloads := requester.GetLinksContents(context.Background(), logger, taskLinks(task))
+		defer services.RemoveDownloads(logger, downloads)
+		for _, download := range downloads {
+			require.NoError(t, download.Err)
+		}
+	}
+
+	// The first task takes the only connection and queues the rest of its links before the second task comes.
+	wg.Add(2)
+	go download("a")
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	go download("b")
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, []string{"a", "a", "b", "a", "b", "a", "b", "b"}, order)
+}
+
+func TestParseHostOverrides(t *testing.T) {
+	overrides, err := services.ParseHostOverrides([]string{"*.example.com=8:2.5:5", "slow.org=1"})
+	require.NoError(t, err)
+	require.Equal(t, []services.HostOverride{
+		{Pattern: "*.example.com", Limit: services.HostLimit{MaxConnections: 8, Rate: 2.5, Burst: 5}},
+		{Pattern: "slow.org", Limit: services.HostLimit{MaxConnections: 1, Burst: 1}},
+	}, overrides)
+
+	for _, value := range []string{"example.com", "=1", "example.com=x", "example.com=1:-1", "example.com=1:1:0", "example.com=1:1:1:1"} {
+		_, err := services.ParseHostOverrides([]string{value})
+		require.Error(t, err, value)
+	}
+
+	cfg := newTestConfig()
+	cfg.HostLimits = []string{"example.com"}
+	_, err = services.NewRequesterService(cfg)
+	require.Error(t, err)
+}
+
+func newHostLimitsConfig() config.Config {
+	cfg := newTestConfig()
+	cfg.TasksBufferSize = 4
+	cfg.LinksInTask = 4
+
+	return cfg
+}
+
+// newConcurrencyServer serves PDF files slowly and records the peak number of requests served at once.
+func newConcurrencyServer(delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+
+	return server, &peak
+}
+
+// localhostURL returns the server URL with the localhost host name instead of the loopback address.
+func localhostURL(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	return "http://localhost:" + port
+}
@@ -7,6 +7,11 @@ import (
 	bp "270725/internal/rest/v1/boileplate"
 	"270725/internal/services"
 	"270725/internal/storage/inmemory"
+	"270725/internal/storage/localfs"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/labstack/echo/v4"
@@ -83,13 +88,61 @@ func TestCreateTaskSuccessfully(t *testing.T) {
 
 	}
 	c, res = createResponser(http.MethodGet, urlPrefix+"/task/"+TaskInfo.Id+"/result", "")
-	err = h.GetResult(c, TaskInfo.Id)
+	err = h.GetResult(c, TaskInfo.Id, bp.GetResultParams{})
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, res.Code)
 	require.Equal(t, "application/zip", res.Header().Get(echo.HeaderContentType))
 
 }
 
+func TestGetResultChecksumHeaders(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+	h := v1.NewHandler(setupTestLogger(), service)
+
+	taskID, err := service.NewTask(context.Background(), "")
+	require.NoError(t, err)
+
+	c, res := createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result", "")
+	require.ErrorIs(t, h.GetResult(c, taskID, bp.GetResultParams{}), services.ErrTaskNotFound)
+
+	taskID = runTask(t, service, server.URL+"/a.pdf")
+	task, err := service.GetTask(context.Background(), taskID)
+	require.NoError(t, err)
+
+	c, res = createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result", "")
+	require.NoError(t, h.GetResult(c, taskID, bp.GetResultParams{}))
+	require.Equal(t, http.StatusOK, res.Code)
+
+	checksum := sha256.Sum256(res.Body.Bytes())
+	require.Equal(t, hex.EncodeToString(checksum[:]), task.ArchiveSHA256)
+	require.Equal(t, int64(res.Body.Len()), task.ArchiveSize)
+	require.Equal(t, `"`+task.ArchiveSHA256+`"`, res.Header().Get("ETag"))
+	require.Equal(t, "sha-256="+base64.StdEncoding.EncodeToString(checksum[:]), res.Header().Get("Digest"))
+
+	c, res = createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result", "")
+	c.Request().Header.Set("If-None-Match", `"`+task.ArchiveSHA256+`"`)
+	require.NoError(t, h.GetResult(c, taskID, bp.GetResultParams{}))
+	require.Equal(t, http.StatusNotModified, res.Code)
+}
+
+func TestGetUsageReportsIdleService(t *testing.T) {
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+	h := v1.NewHandler(setupTestLogger(), service)
+
+	c, res := createResponser(http.MethodGet, urlPrefix+"/usage", "")
+	require.NoError(t, h.GetUsage(c))
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, fmt.Sprintf(
+		`{"createdTasks": 0, "maxCreatedTasks": %d, "activeTasks": 0, "maxActiveTasks": %d}`,
+		cfg.MaxCreatedTasks, cfg.TasksBufferSize+cfg.MaxQueuedTasks,
+	), res.Body.String())
+}
+
 func createResponser(method, url string, body string) (echo.Context, *httptest.ResponseRecorder) {
 	req := httptest.NewRequest(method, url, strings.NewReader(body))
 	if body != "" {
@@ -108,13 +161,19 @@ func setupHandler() *v1.Handler {
 	logger := setupTestLogger()
 	repo := inmemory.NewMemory()
 
-	requester := services.NewRequesterService(int(cfg.TasksBufferSize * cfg.LinksInTask))
-	archiver, err := services.NewZipper(cfg.ArchivesDir)
+	requester, err := services.NewRequesterService(cfg)
+	if err != nil {
+		panic(fmt.Errorf("failed to create requester: %w", err))
+	}
+	results, err := localfs.NewLocal(cfg.ArchivesDir)
 	if err != nil {
-		panic(fmt.Errorf("failed to create archiver: %w", err))
+		panic(fmt.Errorf("failed to create result store: %w", err))
 	}
 
-	taskService := services.NewTaskService(cfg, logger, repo, requester, archiver)
+	taskService := services.NewTaskService(cfg, logger, repo, inmemory.NewQueue(), requester, services.NewArchivers(cfg), results)
+	if err := taskService.Start(context.Background()); err != nil {
+		panic(fmt.Errorf("failed to start task service: %w", err))
+	}
 
 	handler := v1.NewHandler(logger, taskService)
 	v1.RegisterHandler(e, handler)
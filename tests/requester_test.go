@@ -0,0 +1,576 @@
+package tests
+
+import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"270725/internal/services"
+	"270725/internal/storage/localfs"
+	"archive/zip"
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	largeFileSize = 64 << 20
+	pdfHeader     = "%PDF-1.7\n"
+)
+
+func TestRequesterStreamsLargeFiles(t *testing.T) {
+	server := newPayloadServer(largeFileSize)
+	defer server.Close()
+
+	links := []string{server.URL + "/a.pdf", server.URL + "/b.pdf", server.URL + "/c.pdf"}
+	peak := measurePeakHeap(func() {
+		downloadAndArchive(t, links, "task")
+	})
+
+	require.Less(t, peak, uint64(32<<20), "heap must not grow with file size")
+
+	archive, err := zip.OpenReader(filepath.Join("archives", "task"))
+	require.NoError(t, err)
+	defer archive.Close()
+
+	require.Len(t, archive.File, len(links))
+	for _, file := range archive.File {
+		require.Equal(t, uint64(largeFileSize), file.UncompressedSize64)
+	}
+}
+
+func TestRequesterReportsFailureReasons(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	requester := newRequester(t, newTestConfig())
+	downloads := requester.GetLinksContents(context.Background(), slog.New(slog.DiscardHandler), []string{
+		server.URL + "/a.jpg",
+		closed.URL + "/b.jpg",
+	})
+	defer services.RemoveDownloads(slog.New(slog.DiscardHandler), downloads)
+
+	var downloadErr *services.DownloadError
+	require.ErrorAs(t, downloads[0].Err, &downloadErr)
+	require.Equal(t, models.HTTPStatusFailureReason, downloadErr.Reason)
+	require.Equal(t, http.StatusNotFound, downloads[0].StatusCode)
+
+	require.ErrorAs(t, downloads[1].Err, &downloadErr)
+	require.Equal(t, models.ConnectFailureReason, downloadErr.Reason)
+}
+
+func TestRequesterFiltersContentType(t *testing.T) {
+	pngHead := "\x89PNG\r\n\x1a\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.jpg":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, pngHead)
+		case "/unknown.pdf":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = io.WriteString(w, pdfHeader)
+		case "/malware.exe":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = io.WriteString(w, "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff")
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, pdfHeader)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	requester := newRequester(t, newTestConfig())
+	downloads := requester.GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/image.jpg",
+		server.URL + "/unknown.pdf",
+		server.URL + "/malware.exe",
+		server.URL + "/page.html",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	require.NoError(t, downloads[0].Err)
+	require.NoError(t, downloads[1].Err)
+	require.ErrorIs(t, downloads[2].Err, services.ErrDisallowedContentType)
+	require.ErrorIs(t, downloads[3].Err, services.ErrDisallowedContentType)
+	require.Empty(t, downloads[2].Path)
+}
+
+func TestRequesterBlocksInternalAddresses(t *testing.T) {
+	var internalHits atomic.Int32
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	require.NoError(t, err)
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect.pdf" {
+			http.Redirect(w, r, internal.URL+"/secret.pdf", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	logger := slog.New(slog.DiscardHandler)
+
+	cfg := newTestConfig()
+	cfg.AllowedNetworks = nil
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/a.pdf",
+		"http://localhost:" + port + "/a.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	for _, download := range downloads {
+		var downloadErr *services.DownloadError
+		require.ErrorIs(t, download.Err, services.ErrBlockedAddress, download.Link)
+		require.ErrorAs(t, download.Err, &downloadErr)
+		require.Equal(t, models.BlockedAddressFailureReason, downloadErr.Reason)
+		require.Equal(t, uint(1), download.Attempts)
+	}
+
+	// Redirect targets are checked as well, the allowed server can not point to another internal one.
+	cfg.AllowedNetworks = []string{"127.0.0.1"}
+	downloads = newRequester(t, cfg).GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/a.pdf",
+		server.URL + "/redirect.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	require.NoError(t, downloads[0].Err)
+	require.ErrorIs(t, downloads[1].Err, services.ErrBlockedAddress)
+	require.Zero(t, internalHits.Load())
+}
+
+func TestRequesterEnforcesSizeLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		switch r.URL.Path {
+		case "/big.pdf":
+			w.Header().Set("Content-Length", "2048")
+			_, _ = io.CopyN(w, io.MultiReader(strings.NewReader(pdfHeader), zeroReader{}), 2048)
+		case "/endless.pdf":
+			_, _ = io.Copy(w, io.MultiReader(strings.NewReader(pdfHeader), zeroReader{}))
+		default:
+			w.Header().Set("Content-Length", "600")
+			_, _ = io.CopyN(w, io.MultiReader(strings.NewReader(pdfHeader), zeroReader{}), 600)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newTestConfig()
+	cfg.MaxFileSize = 1024
+	cfg.MaxTaskSize = 0
+	requester := newRequester(t, cfg)
+
+	downloads := requester.GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/big.pdf",
+		server.URL + "/endless.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	for _, download := range downloads {
+		var downloadErr *services.DownloadError
+		require.ErrorIs(t, download.Err, services.ErrFileTooLarge, download.Link)
+		require.ErrorAs(t, download.Err, &downloadErr)
+		require.Equal(t, models.SizeLimitFailureReason, downloadErr.Reason)
+		require.Equal(t, int64(1024), downloadErr.Limit)
+		require.Equal(t, uint(1), download.Attempts)
+		require.Empty(t, download.Path)
+	}
+
+	// Three files of 600 bytes do not fit into 1500 bytes together, bytes of the failed file are given back.
+	cfg.MaxTaskSize = 1500
+	requester = newRequester(t, cfg)
+	downloads = requester.GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/a.pdf",
+		server.URL + "/b.pdf",
+		server.URL + "/c.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	var downloaded int64
+	var failed int
+	for _, download := range downloads {
+		if download.Err == nil {
+			downloaded += download.Size
+			continue
+		}
+
+		failed++
+		var downloadErr *services.DownloadError
+		require.ErrorIs(t, download.Err, services.ErrTaskTooLarge)
+		require.ErrorAs(t, download.Err, &downloadErr)
+		require.Equal(t, int64(1500), downloadErr.Limit)
+	}
+	require.NotZero(t, failed)
+	require.LessOrEqual(t, downloaded, int64(1500))
+}
+
+func TestRequesterChecksLinkPolicyOnRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect.pdf":
+			http.Redirect(w, r, "/a.pdf", http.StatusFound)
+		case "/other-host.pdf":
+			http.Redirect(w, r, strings.Replace(r.Host, "127.0.0.1", "http://localhost", 1)+"/a.pdf", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = io.WriteString(w, pdfHeader)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newTestConfig()
+	cfg.AllowedHosts = []string{"127.0.0.1"}
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/redirect.pdf",
+		server.URL + "/other-host.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	require.NoError(t, downloads[0].Err)
+
+	var downloadErr *services.DownloadError
+	require.ErrorIs(t, downloads[1].Err, services.ErrLinkNotAllowed)
+	require.ErrorAs(t, downloads[1].Err, &downloadErr)
+	require.Equal(t, models.DisallowedLinkFailureReason, downloadErr.Reason)
+	require.Equal(t, uint(1), downloads[1].Attempts)
+}
+
+func TestRequesterRedirectPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hops, ok := strings.CutPrefix(r.URL.Path, "/hops/"); ok {
+			left, err := strconv.Atoi(strings.TrimSuffix(hops, ".pdf"))
+			require.NoError(t, err)
+
+			next := "/a.pdf"
+			if left > 1 {
+				next = "/hops/" + strconv.Itoa(left-1) + ".pdf"
+			}
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/other-host.pdf" {
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://localhost:"+port+"/a.pdf", http.StatusFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newTestConfig()
+	cfg.RedirectMaxHops = 2
+	cfg.RedirectSameHost = true
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/hops/2.pdf",
+		server.URL + "/hops/3.pdf",
+		server.URL + "/other-host.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	require.NoError(t, downloads[0].Err)
+	require.Equal(t, server.URL+"/a.pdf", downloads[0].FinalURL)
+	require.Equal(t, []string{server.URL + "/hops/1.pdf", server.URL + "/a.pdf"}, downloads[0].Redirects)
+
+	for _, download := range downloads[1:] {
+		var downloadErr *services.DownloadError
+		require.ErrorIs(t, download.Err, services.ErrRedirectNotAllowed, download.Link)
+		require.ErrorAs(t, download.Err, &downloadErr)
+		require.Equal(t, models.RedirectFailureReason, downloadErr.Reason)
+	}
+	require.Len(t, downloads[1].Redirects, 3)
+	require.Equal(t, []string{"http://localhost:" + port + "/a.pdf"}, downloads[2].Redirects)
+}
+
+func TestRedirectPolicyRefusesDowngrade(t *testing.T) {
+	newRequest := func(link string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, link, nil)
+	}
+	via := []*http.Request{newRequest("https://example.com/a.pdf")}
+
+	policy := services.RedirectPolicy{MaxHops: 10}
+	require.ErrorIs(t, policy.Check(newRequest("http://example.com/a.pdf"), via), services.ErrRedirectNotAllowed)
+	require.NoError(t, policy.Check(newRequest("https://cdn.example.com/a.pdf"), via))
+
+	policy.AllowDowngrade = true
+	require.NoError(t, policy.Check(newRequest("http://example.com/a.pdf"), via))
+}
+
+func TestAddressGuard(t *testing.T) {
+	guard, err := services.NewAddressGuard([]string{"203.0.113.0/24", "198.51.100.7"}, []string{"10.1.0.0/16"})
+	require.NoError(t, err)
+
+	blocked := []string{"127.0.0.1", "::1", "::ffff:127.0.0.1", "169.254.169.254", "fe80::1", "192.168.1.1", "10.2.0.1", "fd00::1", "0.0.0.0", "203.0.113.10", "198.51.100.7"}
+	for _, addr := range blocked {
+		require.ErrorIs(t, guard.Check(netip.MustParseAddr(addr)), services.ErrBlockedAddress, addr)
+	}
+
+	allowed := []string{"8.8.8.8", "2001:4860:4860::8888", "10.1.2.3", "198.51.100.8"}
+	for _, addr := range allowed {
+		require.NoError(t, guard.Check(netip.MustParseAddr(addr)), addr)
+	}
+
+	_, err = services.NewAddressGuard([]string{"10.0.0.0/33"}, nil)
+	require.Error(t, err)
+}
+
+func TestRequesterRetriesTransientFailures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky.pdf":
+			switch requests.Add(1) {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Content-Length", "1024")
+				_, _ = io.WriteString(w, pdfHeader)
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			default:
+				_, _ = io.WriteString(w, pdfHeader)
+			}
+		case "/throttled.pdf":
+			w.Header().Set("Retry-After", "3600")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.DownloadAttempts = 3
+	cfg.DownloadRetryBackoff = time.Millisecond
+	cfg.DownloadRetryMaxBackoff = 10 * time.Millisecond
+
+	logger := slog.New(slog.DiscardHandler)
+	requester := newRequester(t, cfg)
+	downloads := requester.GetLinksContents(context.Background(), logger, []string{
+		server.URL + "/flaky.pdf",
+		server.URL + "/missing.pdf",
+		server.URL + "/throttled.pdf",
+	})
+	defer services.RemoveDownloads(logger, downloads)
+
+	require.NoError(t, downloads[0].Err)
+	require.Equal(t, uint(3), downloads[0].Attempts)
+	require.Equal(t, http.StatusOK, downloads[0].StatusCode)
+
+	require.Error(t, downloads[1].Err)
+	require.Equal(t, uint(1), downloads[1].Attempts)
+
+	require.Error(t, downloads[2].Err)
+	require.Equal(t, uint(1), downloads[2].Attempts, "Retry-After beyond max backoff must not be waited for")
+}
+
+func TestRequesterTimeoutsAndCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	logger := slog.New(slog.DiscardHandler)
+
+	cfg := newTestConfig()
+	cfg.DownloadAttempts = 1
+	cfg.DownloadHeaderTimeout = 50 * time.Millisecond
+	downloads := newRequester(t, cfg).GetLinksContents(context.Background(), logger, []string{server.URL + "/slow.pdf"})
+
+	var downloadErr *services.DownloadError
+	require.ErrorAs(t, downloads[0].Err, &downloadErr)
+	require.Equal(t, models.TimeoutFailureReason, downloadErr.Reason)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	downloads = newRequester(t, newTestConfig()).GetLinksContents(ctx, logger, []string{server.URL + "/hanging.pdf"})
+	require.Less(t, time.Since(started), 5*time.Second)
+	require.ErrorAs(t, downloads[0].Err, &downloadErr)
+	require.Equal(t, models.CancelledFailureReason, downloadErr.Reason)
+}
+
+func TestRequesterShutdownWaitsDownloads(t *testing.T) {
+	downloading := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(downloading)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	requester := newRequester(t, newTestConfig())
+
+	done := make(chan []*services.DownloadResult, 1)
+	go func() {
+		done <- requester.GetLinksContents(context.Background(), logger, []string{server.URL + "/a.pdf"})
+	}()
+	<-downloading
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, requester.Shutdown(ctx))
+
+	downloads := <-done
+	defer services.RemoveDownloads(logger, downloads)
+	require.NoError(t, downloads[0].Err)
+
+	downloads = requester.GetLinksContents(context.Background(), logger, []string{server.URL + "/b.pdf"})
+	require.Error(t, downloads[0].Err)
+}
+
+func BenchmarkRequesterStreaming(b *testing.B) {
+	server := newPayloadServer(largeFileSize)
+	defer server.Close()
+
+	links := []string{server.URL + "/a.pdf", server.URL + "/b.pdf", server.URL + "/c.pdf"}
+
+	b.SetBytes(int64(len(links)) * largeFileSize)
+	b.ReportAllocs()
+
+	var peak uint64
+	for i := 0; b.Loop(); i++ {
+		peak = max(peak, measurePeakHeap(func() {
+			downloadAndArchive(b, links, strconv.Itoa(i))
+		}))
+	}
+
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+}
+
+func downloadAndArchive(tb testing.TB, links []string, name string) {
+	tb.Helper()
+	tb.Chdir(tb.TempDir())
+
+	logger := slog.New(slog.DiscardHandler)
+	cfg := newTestConfig()
+	requester := newRequester(tb, cfg)
+	results, err := localfs.NewLocal("archives")
+	require.NoError(tb, err)
+
+	downloads := requester.GetLinksContents(context.Background(), logger, links)
+	defer services.RemoveDownloads(logger, downloads)
+
+	files := make([]services.ArchiveFile, 0, len(downloads))
+	for _, download := range downloads {
+		require.NoError(tb, download.Err)
+		require.Equal(tb, int64(largeFileSize), download.Size)
+		files = append(files, services.ArchiveFile{Name: filepath.Base(download.Link), Path: download.Path})
+	}
+
+	_, err = services.WriteArchive(context.Background(), services.NewArchivers(cfg)[models.ZipArchiveFormat], results, name, files)
+	require.NoError(tb, err)
+}
+
+// measurePeakHeap returns the maximum heap growth observed while fn runs.
+func measurePeakHeap(fn func()) uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapInuse
+
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > base && stats.HeapInuse-base > peak.Load() {
+				peak.Store(stats.HeapInuse - base)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	close(done)
+	<-stopped
+
+	return peak.Load()
+}
+
+func newPayloadServer(size int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		_, _ = io.CopyN(w, io.MultiReader(strings.NewReader(pdfHeader), zeroReader{}), size)
+	}))
+}
+
+func newRequester(tb testing.TB, cfg config.Config) *services.Requester {
+	tb.Helper()
+
+	requester, err := services.NewRequesterService(cfg)
+	require.NoError(tb, err)
+
+	return requester
+}
+
+func newTestConfig() config.Config {
+	cfg := config.MustLoad()
+	cfg.AllowedExtensions = []string{"jpg", "png", "pdf"}
+	// Test servers listen on loopback.
+	cfg.AllowedNetworks = []string{"127.0.0.0/8", "::1/128"}
+	cfg.DownloadRetryBackoff = time.Millisecond
+
+	return cfg
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
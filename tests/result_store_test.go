@@ -0,0 +1,347 @@
+package tests
+
+import (
+	"270725/internal/config"
+	v1 "270725/internal/rest/v1"
+	bp "270725/internal/rest/v1/boileplate"
+	"270725/internal/services"
+	"270725/internal/storage"
+	"270725/internal/storage/inmemory"
+	"270725/internal/storage/localfs"
+	"270725/internal/storage/s3"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	s3AccessKey = "test-access-key"
+	s3SecretKey = "test-secret-key"
+)
+
+func TestLocalResultStore(t *testing.T) {
+	testResultStore(t, func(t *testing.T) services.ResultStore {
+		results, err := localfs.NewLocal(filepath.Join(t.TempDir(), "archives"))
+		require.NoError(t, err)
+
+		return results
+	})
+}
+
+func TestS3ResultStore(t *testing.T) {
+	testResultStore(t, func(t *testing.T) services.ResultStore {
+		return newS3ResultStore(t, newS3StandIn(t))
+	})
+}
+
+func TestLocalResultStoreRemovesUnfinishedResults(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crashed-123.tmp"), []byte("PK"), 0o644))
+
+	_, err := localfs.NewLocal(dir)
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(dir, "crashed-123.tmp"))
+}
+
+func TestS3ResultRedirect(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.ResultRedirect = true
+	service := newStoredTaskService(t, cfg, newS3ResultStore(t, newS3StandIn(t)))
+
+	taskID := runTask(t, service, server.URL+"/a.pdf")
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+
+	result, err := service.GetTaskResult(ctx, taskID, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, result.URL)
+	require.Nil(t, result.Content)
+
+	c, res := createResponser(http.MethodGet, urlPrefix+"/task/"+taskID+"/result", "")
+	require.NoError(t, v1.NewHandler(setupTestLogger(), service).GetResult(c, taskID, bp.GetResultParams{}))
+	require.Equal(t, http.StatusTemporaryRedirect, res.Code)
+	require.Equal(t, result.URL, res.Header().Get("Location"))
+
+	response, err := http.Get(result.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Contains(t, response.Header.Get("Content-Disposition"), taskID)
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	checksum := sha256.Sum256(body)
+	require.Equal(t, task.ArchiveSHA256, hex.EncodeToString(checksum[:]))
+
+	require.NoError(t, service.DeleteTask(ctx, taskID))
+	_, err = service.GetTaskResult(ctx, taskID, "")
+	require.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+func TestLocalResultRedirectFallsBackToStreaming(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	cfg := newServiceConfig(t)
+	cfg.ResultRedirect = true
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID := runTask(t, service, server.URL+"/a.pdf")
+
+	result, err := service.GetTaskResult(context.Background(), taskID, "")
+	require.NoError(t, err)
+	defer result.Content.Close()
+	require.Empty(t, result.URL)
+	require.NotNil(t, result.Content)
+}
+
+func testResultStore(t *testing.T, newStore func(t *testing.T) services.ResultStore) {
+	ctx := context.Background()
+
+	t.Run("put get stat delete", func(t *testing.T) {
+		results := newStore(t)
+		content := []byte(pdfHeader + "result")
+
+		_, err := results.Stat(ctx, "task")
+		require.ErrorIs(t, err, storage.ErrResultNotFound)
+		_, err = results.Get(ctx, "task")
+		require.ErrorIs(t, err, storage.ErrResultNotFound)
+
+		path := filepath.Join(t.TempDir(), "archive")
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		require.NoError(t, results.Put(ctx, "task", path))
+
+		info, err := results.Stat(ctx, "task")
+		require.NoError(t, err)
+		require.Equal(t, int64(len(content)), info.Size)
+		require.False(t, info.ModTime.IsZero())
+
+		reader, err := results.Get(ctx, "task")
+		require.NoError(t, err)
+		got, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, content, got)
+
+		_, err = reader.Seek(int64(len(pdfHeader)), io.SeekStart)
+		require.NoError(t, err)
+		got, err = io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "result", string(got))
+		require.NoError(t, reader.Close())
+
+		require.NoError(t, results.Delete(ctx, "task"))
+		require.NoError(t, results.Delete(ctx, "task"))
+		_, err = results.Stat(ctx, "task")
+		require.ErrorIs(t, err, storage.ErrResultNotFound)
+	})
+
+	t.Run("put replaces result", func(t *testing.T) {
+		results := newStore(t)
+
+		for _, content := range []string{"first", "second"} {
+			path := filepath.Join(t.TempDir(), "archive")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			require.NoError(t, results.Put(ctx, "task", path))
+		}
+
+		reader, err := results.Get(ctx, "task")
+		require.NoError(t, err)
+		defer reader.Close()
+		got, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "second", string(got))
+	})
+
+	t.Run("presign", func(t *testing.T) {
+		results := newStore(t)
+
+		path := filepath.Join(t.TempDir(), "archive")
+		require.NoError(t, os.WriteFile(path, []byte("content"), 0o644))
+		require.NoError(t, results.Put(ctx, "task", path))
+
+		link, err := results.Presign(ctx, "task", "task.zip", time.Minute)
+		if err != nil {
+			require.ErrorIs(t, err, storage.ErrPresignNotSupported)
+			return
+		}
+
+		response, err := http.Get(link)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, `attachment; filename=task.zip`, response.Header.Get("Content-Disposition"))
+
+		got, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, "content", string(got))
+	})
+}
+
+func newS3ResultStore(t *testing.T, server *httptest.Server) *s3.S3 {
+	t.Helper()
+
+	results, err := s3.NewS3(context.Background(), config.S3Config{
+		S3Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		S3Bucket:    "archives",
+		S3AccessKey: s3AccessKey,
+		S3SecretKey: s3SecretKey,
+		S3Region:    "us-east-1",
+	})
+	require.NoError(t, err)
+
+	return results
+}
+
+// newS3StandIn starts a minimal in-memory S3-compatible server, it supports path-style bucket
+// and object requests and checks that every request is signed with the test access key.
+func newS3StandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	type object struct {
+		content  []byte
+		modified time.Time
+	}
+
+	var mu sync.Mutex
+	buckets := make(map[string]map[string]object)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s3Signed(r) {
+			writeS3Error(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		objects, ok := buckets[bucket]
+		if key == "" {
+			switch {
+			case r.Method == http.MethodPut:
+				buckets[bucket] = make(map[string]object)
+			case !ok:
+				writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+			case r.URL.Query().Has("location"):
+				_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`)
+			}
+			return
+		}
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			content, err := readS3Body(r)
+			if err != nil {
+				writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+				return
+			}
+
+			objects[key] = object{content: content, modified: time.Now().UTC().Truncate(time.Second)}
+			w.Header().Set("ETag", strconv.Quote(fmt.Sprintf("%x", md5.Sum(content))))
+		case http.MethodGet, http.MethodHead:
+			obj, ok := objects[key]
+			if !ok {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+
+			if disposition := r.URL.Query().Get("response-content-disposition"); disposition != "" {
+				w.Header().Set("Content-Disposition", disposition)
+			}
+			w.Header().Set("ETag", strconv.Quote(fmt.Sprintf("%x", md5.Sum(obj.content))))
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeContent(w, r, key, obj.modified, bytes.NewReader(obj.content))
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// s3Signed reports whether the request is signed by header or by presigned query with the test access key.
+func s3Signed(r *http.Request) bool {
+	credential := "Credential=" + s3AccessKey + "/"
+	if strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "+credential) {
+		return true
+	}
+
+	query := r.URL.Query()
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), s3AccessKey+"/") || query.Get("X-Amz-Signature") == "" {
+		return false
+	}
+
+	signed, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+
+	return err == nil && time.Now().Before(signed.Add(time.Duration(expires)*time.Second))
+}
+
+// readS3Body reads the object body, decoding aws-chunked streaming uploads.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var content bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&content, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
@@ -0,0 +1,296 @@
+package tests
+
+import (
+	"270725/internal/models"
+	"270725/internal/services"
+	"270725/internal/storage"
+	"270725/internal/storage/boltdb"
+	"270725/internal/storage/inmemory"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	testTaskRepository(t, func(t *testing.T) services.TaskRepository {
+		return inmemory.NewMemory()
+	})
+}
+
+func TestBoltRepository(t *testing.T) {
+	testTaskRepository(t, func(t *testing.T) services.TaskRepository {
+		return newBoltRepository(t, filepath.Join(t.TempDir(), "tasks.db"))
+	})
+}
+
+func TestBoltRepositoryPersistsTasks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	repo, err := boltdb.NewBolt(path)
+	require.NoError(t, err)
+
+	taskID, err := repo.NewTask(ctx)
+	require.NoError(t, err)
+	_, err = repo.AddLinksToTask(ctx, taskID, []*models.FileLink{
+		{Link: "https://example.com/a.jpg", Status: models.NewTaskLinkStatus},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	repo = newBoltRepository(t, path)
+	task, err := repo.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Len(t, task.FilesLink, 1)
+	require.Equal(t, "https://example.com/a.jpg", task.FilesLink[0].Link)
+}
+
+func TestMemoryQueue(t *testing.T) {
+	testTaskQueue(t, func(t *testing.T) services.TaskQueue {
+		return inmemory.NewQueue()
+	})
+}
+
+func TestBoltQueue(t *testing.T) {
+	testTaskQueue(t, func(t *testing.T) services.TaskQueue {
+		return newBoltQueue(t, newBoltRepository(t, filepath.Join(t.TempDir(), "tasks.db")))
+	})
+}
+
+func TestBoltQueuePersistsTasks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	repo, err := boltdb.NewBolt(path)
+	require.NoError(t, err)
+	queue := newBoltQueue(t, repo)
+	require.NoError(t, queue.Push(ctx, "first"))
+	require.NoError(t, queue.Push(ctx, "second"))
+	require.NoError(t, repo.Close())
+
+	queue = newBoltQueue(t, newBoltRepository(t, path))
+	taskID, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "first", taskID)
+}
+
+func testTaskQueue(t *testing.T, newQueue func(t *testing.T) services.TaskQueue) {
+	ctx := context.Background()
+
+	t.Run("fifo order and positions", func(t *testing.T) {
+		queue := newQueue(t)
+
+		for _, taskID := range []string{"a", "b", "c"} {
+			require.NoError(t, queue.Push(ctx, taskID))
+		}
+
+		position, err := queue.Position(ctx, "c")
+		require.NoError(t, err)
+		require.Equal(t, 3, position)
+
+		require.NoError(t, queue.Remove(ctx, "b"))
+		position, err = queue.Position(ctx, "c")
+		require.NoError(t, err)
+		require.Equal(t, 2, position)
+
+		position, err = queue.Position(ctx, "b")
+		require.NoError(t, err)
+		require.Zero(t, position)
+
+		for _, expected := range []string{"a", "c"} {
+			taskID, err := queue.Pop(ctx)
+			require.NoError(t, err)
+			require.Equal(t, expected, taskID)
+		}
+	})
+
+	t.Run("pop waits for push", func(t *testing.T) {
+		queue := newQueue(t)
+
+		popped := make(chan string)
+		go func() {
+			taskID, _ := queue.Pop(ctx)
+			popped <- taskID
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, queue.Push(ctx, "a"))
+
+		select {
+		case taskID := <-popped:
+			require.Equal(t, "a", taskID)
+		case <-time.After(time.Second):
+			t.Fatal("pop did not return pushed task")
+		}
+	})
+
+	t.Run("pop cancelled", func(t *testing.T) {
+		queue := newQueue(t)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := queue.Pop(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func testTaskRepository(t *testing.T, newRepo func(t *testing.T) services.TaskRepository) {
+	ctx := context.Background()
+
+	t.Run("new task", func(t *testing.T) {
+		repo := newRepo(t)
+
+		taskID, err := repo.NewTask(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, taskID)
+
+		task, err := repo.GetTask(ctx, taskID)
+		require.NoError(t, err)
+		require.Equal(t, taskID, task.ID)
+		require.Equal(t, models.CreatedTaskStatus, task.Status)
+		require.False(t, task.CreatedAt.IsZero())
+		require.Empty(t, task.FilesLink)
+	})
+
+	t.Run("get all tasks", func(t *testing.T) {
+		repo := newRepo(t)
+
+		ids := make([]string, 0, 3)
+		for range 3 {
+			taskID, err := repo.NewTask(ctx)
+			require.NoError(t, err)
+			ids = append(ids, taskID)
+		}
+
+		tasks, err := repo.GetAllTasks(ctx)
+		require.NoError(t, err)
+
+		gotIDs := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			gotIDs = append(gotIDs, task.ID)
+		}
+		require.ElementsMatch(t, ids, gotIDs)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetTask(ctx, "unknown")
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+
+		_, err = repo.AddLinksToTask(ctx, "unknown", nil)
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+
+		_, err = repo.UpdateTask(ctx, "unknown", func(*models.Task) error { return nil })
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+
+		err = repo.MarkTaskLinksInProcessStatus(ctx, "unknown")
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+
+		err = repo.MarkTaskLinksCompleted(ctx, "unknown", nil)
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+
+		err = repo.DeleteTask(ctx, "unknown")
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+	})
+
+	t.Run("delete task", func(t *testing.T) {
+		repo := newRepo(t)
+
+		taskID, err := repo.NewTask(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.DeleteTask(ctx, taskID))
+
+		_, err = repo.GetTask(ctx, taskID)
+		require.ErrorIs(t, err, storage.ErrTaskNotFound)
+	})
+
+	t.Run("update task", func(t *testing.T) {
+		repo := newRepo(t)
+
+		taskID, err := repo.NewTask(ctx)
+		require.NoError(t, err)
+
+		startedAt := time.Now().UTC().Truncate(time.Millisecond)
+		task, err := repo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+			task.SetStatus(models.ProcessingTaskStatus, startedAt)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.ProcessingTaskStatus, task.Status)
+
+		errUpdate := errors.New("update failed")
+		_, err = repo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+			task.SetStatus(models.FailedTaskStatus, time.Now())
+			return errUpdate
+		})
+		require.ErrorIs(t, err, errUpdate)
+
+		task, err = repo.GetTask(ctx, taskID)
+		require.NoError(t, err)
+		require.Equal(t, models.ProcessingTaskStatus, task.Status)
+		require.True(t, startedAt.Equal(task.StartedAt))
+		require.True(t, task.FinishedAt.IsZero())
+	})
+
+	t.Run("links lifecycle", func(t *testing.T) {
+		repo := newRepo(t)
+
+		taskID, err := repo.NewTask(ctx)
+		require.NoError(t, err)
+
+		links := []*models.FileLink{
+			{Link: "https://example.com/a.jpg", Status: models.NewTaskLinkStatus},
+		}
+		task, err := repo.AddLinksToTask(ctx, taskID, links)
+		require.NoError(t, err)
+		require.Len(t, task.FilesLink, 1)
+
+		// The repository keeps its own copies of the links.
+		links[0].Status = models.ErrorTaskLinkStatus
+		require.Equal(t, models.NewTaskLinkStatus, task.FilesLink[0].Status)
+
+		task, err = repo.AddLinksToTask(ctx, taskID, []*models.FileLink{
+			{Link: "https://example.com/b.png", Status: models.NewTaskLinkStatus},
+		})
+		require.NoError(t, err)
+		require.Len(t, task.FilesLink, 2)
+
+		require.NoError(t, repo.MarkTaskLinksInProcessStatus(ctx, taskID))
+		task, err = repo.GetTask(ctx, taskID)
+		require.NoError(t, err)
+		for _, link := range task.FilesLink {
+			require.Equal(t, models.InProcessTaskLinkStatus, link.Status)
+		}
+
+		require.NoError(t, repo.MarkTaskLinksCompleted(ctx, taskID, []string{"https://example.com/b.png"}))
+		task, err = repo.GetTask(ctx, taskID)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/a.jpg", task.FilesLink[0].Link)
+		require.Equal(t, models.ErrorTaskLinkStatus, task.FilesLink[0].Status)
+		require.Equal(t, "https://example.com/b.png", task.FilesLink[1].Link)
+		require.Equal(t, models.CompletedTaskLinkStatus, task.FilesLink[1].Status)
+	})
+}
+
+func newBoltQueue(t *testing.T, repo *boltdb.Bolt) *boltdb.Queue {
+	queue, err := boltdb.NewQueue(repo)
+	require.NoError(t, err)
+
+	return queue
+}
+
+func newBoltRepository(t *testing.T, path string) *boltdb.Bolt {
+	repo, err := boltdb.NewBolt(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = repo.Close()
+	})
+
+	return repo
+}
@@ -0,0 +1,1043 @@
+package tests
+
+import (
+	"270725/internal/config"
+	"270725/internal/models"
+	"270725/internal/services"
+	"270725/internal/storage/boltdb"
+	"270725/internal/storage/inmemory"
+	"270725/internal/storage/localfs"
+	"archive/zip"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartPartiallyFilledTask(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.LinksInTask = 3
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	task, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}})
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+
+	task, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+	require.NotEqual(t, models.CreatedTaskStatus, task.Status)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+}
+
+func TestStartTaskWithoutLinks(t *testing.T) {
+	ctx := context.Background()
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+
+	_, err = service.StartTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrValidation)
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+
+	_, err = service.StartTask(ctx, "unknown")
+	require.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+func TestStartTaskTwice(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+
+	taskID := runTask(t, service, server.URL+"/a.pdf")
+
+	_, err := service.StartTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+}
+
+func TestAutoStartFullTask(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.LinksInTask = 2
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	task, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}})
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+
+	task, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/b.pdf"}})
+	require.NoError(t, err)
+	require.NotEqual(t, models.CreatedTaskStatus, task.Status)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+}
+
+func TestAutoStartDisabledKeepsFullTask(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.LinksInTask = 1
+	cfg.AutoStartTask = false
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	task, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}})
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/b.pdf"}})
+	require.ErrorIs(t, err, services.ErrValidation)
+
+	time.Sleep(50 * time.Millisecond)
+	task, err = service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+	require.False(t, task.CreatedAt.IsZero())
+	require.True(t, task.StartedAt.IsZero())
+	require.True(t, task.FinishedAt.IsZero())
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}})
+	require.NoError(t, err)
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/b.pdf"}})
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+	task, err = service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Len(t, task.FilesLink, 1)
+	require.False(t, task.StartedAt.IsZero())
+	require.False(t, task.FinishedAt.Before(task.StartedAt))
+
+	_, err = service.CancelTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrInvalidTaskStatus)
+}
+
+func TestCancelledTaskCannotBeStarted(t *testing.T) {
+	ctx := context.Background()
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/a.pdf"}})
+	require.NoError(t, err)
+
+	task, err := service.CancelTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CancelledTaskStatus, task.Status)
+	require.True(t, task.StartedAt.IsZero())
+	require.False(t, task.FinishedAt.IsZero())
+
+	_, err = service.StartTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/b.pdf"}})
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+}
+
+// staleTaskRepository returns the task as it was before it was started, like a read racing with a start.
+type staleTaskRepository struct {
+	*inmemory.Memory
+	stale *models.Task
+}
+
+func (r *staleTaskRepository) GetTask(ctx context.Context, id string) (*models.Task, error) {
+	if r.stale != nil && r.stale.ID == id {
+		return r.stale.Clone(), nil
+	}
+
+	return r.Memory.GetTask(ctx, id)
+}
+
+// hookTaskRepository calls afterUpdate with every task it stores through UpdateTask.
+type hookTaskRepository struct {
+	*inmemory.Memory
+	afterUpdate func(task *models.Task)
+}
+
+func (r *hookTaskRepository) UpdateTask(
+	ctx context.Context,
+	taskID string,
+	update func(task *models.Task) error,
+) (*models.Task, error) {
+	task, err := r.Memory.UpdateTask(ctx, taskID, update)
+	if err == nil && r.afterUpdate != nil {
+		r.afterUpdate(task.Clone())
+	}
+
+	return task, err
+}
+
+func TestCancelTaskRightAfterProcessingStarts(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := &hookTaskRepository{Memory: inmemory.NewMemory()}
+	service := newTaskService(t, newServiceConfig(t), repo)
+
+	var cancelOnce sync.Once
+	repo.afterUpdate = func(task *models.Task) {
+		if task.Status == models.ProcessingTaskStatus {
+			cancelOnce.Do(func() {
+				_, err := service.CancelTask(ctx, task.ID)
+				require.NoError(t, err)
+			})
+		}
+	}
+
+	taskID := startTask(t, service, server.URL+"/a.pdf")
+	waitTaskStatus(t, service, taskID, models.CancelledTaskStatus)
+
+	// The worker must not mark the cancelled links as in process.
+	time.Sleep(100 * time.Millisecond)
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CancelledTaskStatus, task.Status)
+	require.Equal(t, models.ErrorTaskLinkStatus, task.FilesLink[0].Status)
+	require.Equal(t, models.CancelledFailureReason, task.FilesLink[0].FailureReason)
+}
+
+func TestAddLinksToStartedTaskRejectedOnWrite(t *testing.T) {
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.TasksBufferSize = 0
+	repo := &staleTaskRepository{Memory: inmemory.NewMemory()}
+	service := newTaskService(t, cfg, repo)
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	stale, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/a.pdf"}})
+	require.NoError(t, err)
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+
+	repo.stale = stale
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/b.pdf"}})
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+
+	repo.stale = nil
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.QueuedTaskStatus, task.Status)
+	require.Len(t, task.FilesLink, 1)
+}
+
+func TestCancelProcessingTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}})
+	require.NoError(t, err)
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+
+	waitTaskStatus(t, service, taskID, models.ProcessingTaskStatus)
+
+	task, err := service.CancelTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CancelledTaskStatus, task.Status)
+	require.False(t, task.FinishedAt.IsZero())
+	require.Equal(t, models.CancelledFailureReason, task.FilesLink[0].FailureReason)
+
+	_, err = service.CancelTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrInvalidTaskStatus)
+
+	// The worker must not overwrite the cancelled state once downloads are aborted.
+	time.Sleep(100 * time.Millisecond)
+	task, err = service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CancelledTaskStatus, task.Status)
+	require.NoFileExists(t, filepath.Join(cfg.ArchivesDir, taskID))
+}
+
+func TestJanitorDeletesExpiredTasks(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.ArchiveTTL = time.Millisecond
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID := runTask(t, service, server.URL+"/a.pdf")
+	require.FileExists(t, filepath.Join(cfg.ArchivesDir, taskID))
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, task.FinishedAt.Add(cfg.ArchiveTTL), task.ExpiresAt)
+
+	time.Sleep(5 * time.Millisecond)
+	services.NewJanitor(cfg, slog.New(slog.DiscardHandler), service).Cleanup(ctx)
+
+	_, err = service.GetTask(ctx, taskID)
+	require.ErrorIs(t, err, services.ErrTaskNotFound)
+	require.NoFileExists(t, filepath.Join(cfg.ArchivesDir, taskID))
+}
+
+func TestJanitorEnforcesArchivesQuota(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	oldTaskID := runTask(t, service, server.URL+"/a.pdf")
+	newTaskID := runTask(t, service, server.URL+"/b.pdf")
+
+	info, err := os.Stat(filepath.Join(cfg.ArchivesDir, newTaskID))
+	require.NoError(t, err)
+	cfg.ArchivesQuota = info.Size()
+	services.NewJanitor(cfg, slog.New(slog.DiscardHandler), service).Cleanup(ctx)
+
+	_, err = service.GetTask(ctx, oldTaskID)
+	require.ErrorIs(t, err, services.ErrTaskNotFound)
+	require.NoFileExists(t, filepath.Join(cfg.ArchivesDir, oldTaskID))
+
+	_, err = service.GetTask(ctx, newTaskID)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(cfg.ArchivesDir, newTaskID))
+}
+
+func TestAdmissionLimitsCreatedTasks(t *testing.T) {
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.MaxCreatedTasks = 2
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskIDs := make([]string, 0, cfg.MaxCreatedTasks)
+	for range cfg.MaxCreatedTasks {
+		taskID, err := service.NewTask(ctx, "")
+		require.NoError(t, err)
+		taskIDs = append(taskIDs, taskID)
+	}
+
+	_, err := service.NewTask(ctx, "")
+	require.ErrorIs(t, err, services.ErrServiceBusy)
+	require.Equal(t, services.AdmissionUsage{Created: 2, MaxCreated: 2, MaxActive: int(cfg.TasksBufferSize + cfg.MaxQueuedTasks)}, service.Usage())
+
+	_, err = service.CancelTask(ctx, taskIDs[0])
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteTask(ctx, taskIDs[1]))
+	require.Zero(t, service.Usage().Created)
+
+	_, err = service.NewTask(ctx, "")
+	require.NoError(t, err)
+}
+
+func TestAdmissionReleasesFinishedAndAbandonedTasks(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.CreatedTaskTimeout = time.Millisecond
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	runTask(t, service, server.URL+"/a.pdf")
+	require.Eventually(t, func() bool {
+		return service.Usage().Active == 0
+	}, time.Second, 10*time.Millisecond)
+
+	abandonedID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, service.Usage().Created)
+
+	time.Sleep(5 * time.Millisecond)
+	services.NewJanitor(cfg, slog.New(slog.DiscardHandler), service).Cleanup(ctx)
+
+	require.Zero(t, service.Usage().Created)
+	task, err := service.GetTask(ctx, abandonedID)
+	require.NoError(t, err)
+	require.Equal(t, models.CancelledTaskStatus, task.Status)
+}
+
+func TestAdmissionKeepsSlotsOnRepeatedStart(t *testing.T) {
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.TasksBufferSize = 0
+	cfg.MaxQueuedTasks = 1
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	newTask := func() string {
+		taskID, err := service.NewTask(ctx, "")
+		require.NoError(t, err)
+		_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/a.pdf"}})
+		require.NoError(t, err)
+
+		return taskID
+	}
+
+	queuedID := newTask()
+	_, err := service.StartTask(ctx, queuedID)
+	require.NoError(t, err)
+
+	_, err = service.StartTask(ctx, queuedID)
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+	require.Equal(t, 1, service.Usage().Active)
+	require.Zero(t, service.Usage().Created)
+
+	// The only active slot is still taken, so another task is not admitted.
+	otherID := newTask()
+	_, err = service.StartTask(ctx, otherID)
+	require.ErrorIs(t, err, services.ErrServiceBusy)
+	_, err = service.CancelTask(ctx, otherID)
+	require.NoError(t, err)
+
+	_, err = service.CancelTask(ctx, queuedID)
+	require.NoError(t, err)
+	require.Zero(t, service.Usage().Active)
+
+	_, err = service.StartTask(ctx, queuedID)
+	require.ErrorIs(t, err, services.ErrTaskStarted)
+	require.Zero(t, service.Usage().Active)
+	require.Zero(t, service.Usage().Created)
+}
+
+func TestQueuedTasksProcessedInOrder(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocking.pdf" {
+			<-release
+		}
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.TasksBufferSize = 1
+	cfg.MaxQueuedTasks = 2
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	startTask := func(link string) (*models.Task, error) {
+		taskID, err := service.NewTask(ctx, "")
+		require.NoError(t, err)
+		_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: link}})
+		require.NoError(t, err)
+
+		return service.StartTask(ctx, taskID)
+	}
+
+	blocking, err := startTask(server.URL + "/blocking.pdf")
+	require.NoError(t, err)
+	waitTaskStatus(t, service, blocking.ID, models.ProcessingTaskStatus)
+
+	first, err := startTask(server.URL + "/first.pdf")
+	require.NoError(t, err)
+	require.Equal(t, 1, first.QueuePosition)
+
+	second, err := startTask(server.URL + "/second.pdf")
+	require.NoError(t, err)
+	require.Equal(t, 2, second.QueuePosition)
+
+	_, err = startTask(server.URL + "/third.pdf")
+	require.ErrorIs(t, err, services.ErrServiceBusy)
+
+	close(release)
+	waitTaskStatus(t, service, second.ID, models.ArchivedTaskStatus)
+
+	firstTask, err := service.GetTask(ctx, first.ID)
+	require.NoError(t, err)
+	secondTask, err := service.GetTask(ctx, second.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedTaskStatus, firstTask.Status)
+	require.Zero(t, secondTask.QueuePosition)
+	require.False(t, firstTask.StartedAt.After(secondTask.StartedAt))
+}
+
+func TestRecoverTaskInterruptedByCrash(t *testing.T) {
+	var crashed atomic.Bool
+	downloading := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !crashed.Load() {
+			downloading <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.StoragePath = "tasks.db"
+
+	repo, err := boltdb.NewBolt(cfg.StoragePath)
+	require.NoError(t, err)
+	queue, err := boltdb.NewQueue(repo)
+	require.NoError(t, err)
+	service := newQueuedTaskService(t, cfg, repo, queue)
+
+	taskID := startTask(t, service, server.URL+"/a.pdf")
+	<-downloading
+
+	// Closing the database first leaves the task exactly as a killed process would, nothing is written after it.
+	require.NoError(t, repo.Close())
+	crashed.Store(true)
+	service.Stop()
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.ArchivesDir, taskID), []byte("PK\x03\x04truncated"), 0o644))
+
+	repo = newBoltRepository(t, cfg.StoragePath)
+	task, err := repo.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingTaskStatus, task.Status)
+	require.Equal(t, models.InProcessTaskLinkStatus, task.FilesLink[0].Status)
+
+	service = newQueuedTaskService(t, cfg, repo, newBoltQueue(t, repo))
+	require.Equal(t, 1, service.Usage().Active)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+
+	task, err = service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CompletedTaskLinkStatus, task.FilesLink[0].Status)
+
+	archive, err := zip.OpenReader(filepath.Join(cfg.ArchivesDir, taskID))
+	require.NoError(t, err)
+	defer archive.Close()
+	require.Len(t, archive.File, 1)
+}
+
+func TestRecoverQueuedTasks(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	repo := inmemory.NewMemory()
+
+	// Tasks queued before a restart with a non persistent queue are lost from the queue, but not from the repository.
+	taskID, err := repo.NewTask(ctx)
+	require.NoError(t, err)
+	_, err = repo.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf", Status: models.NewTaskLinkStatus}})
+	require.NoError(t, err)
+	_, err = repo.UpdateTask(ctx, taskID, func(task *models.Task) error {
+		task.Status = models.QueuedTaskStatus
+		return nil
+	})
+	require.NoError(t, err)
+
+	service := newTaskService(t, cfg, repo)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+}
+
+func TestShutdownWaitsRunningTasks(t *testing.T) {
+	downloading := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloading <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID := startTask(t, service, server.URL+"/a.pdf")
+	<-downloading
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, service.Shutdown(shutdownCtx))
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedTaskStatus, task.Status)
+	require.FileExists(t, filepath.Join(cfg.ArchivesDir, taskID))
+
+	_, err = service.NewTask(ctx, "")
+	require.ErrorIs(t, err, services.ErrServiceBusy)
+}
+
+func TestShutdownRefusesAutoStart(t *testing.T) {
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.LinksInTask = 1
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	require.NoError(t, service.Shutdown(ctx))
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: "http://127.0.0.1/a.pdf"}})
+	require.ErrorIs(t, err, services.ErrServiceBusy)
+	require.Zero(t, service.Usage().Active)
+	require.Equal(t, 1, service.Usage().Created)
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CreatedTaskStatus, task.Status)
+	require.Empty(t, task.FilesLink)
+}
+
+func TestShutdownRequeuesUnfinishedTasks(t *testing.T) {
+	var stopped atomic.Bool
+	downloading := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !stopped.Load() && r.URL.Path != "/uploaded.pdf" {
+			select {
+			case downloading <- struct{}{}:
+			default:
+			}
+			<-r.Context().Done()
+			return
+		}
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.StoragePath = "tasks.db"
+	repo := newBoltRepository(t, cfg.StoragePath)
+	queue := newBoltQueue(t, repo)
+	local, err := localfs.NewLocal(cfg.ArchivesDir)
+	require.NoError(t, err)
+	results := &blockingResultStore{ResultStore: local, uploading: make(chan struct{}, 1)}
+	service := startTaskService(t, cfg, repo, queue, results)
+
+	// The first task is aborted while its archive is uploaded, the next one while it is downloaded.
+	uploading := startTask(t, service, server.URL+"/uploaded.pdf")
+	<-results.uploading
+	running := startTask(t, service, server.URL+"/a.pdf")
+	<-downloading
+	queued := startTask(t, service, server.URL+"/b.pdf")
+	queued2 := startTask(t, service, server.URL+"/c.pdf")
+	queued3 := startTask(t, service, server.URL+"/d.pdf")
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, service.Shutdown(shutdownCtx), context.DeadlineExceeded)
+	stopped.Store(true)
+
+	for _, taskID := range []string{uploading, running, queued, queued2, queued3} {
+		task, err := repo.GetTask(ctx, taskID)
+		require.NoError(t, err)
+		require.Equal(t, models.QueuedTaskStatus, task.Status)
+		require.Equal(t, models.NewTaskLinkStatus, task.FilesLink[0].Status)
+	}
+	require.NoFileExists(t, filepath.Join(cfg.ArchivesDir, uploading))
+	require.NoFileExists(t, filepath.Join(cfg.ArchivesDir, running))
+
+	service = newQueuedTaskService(t, cfg, repo, queue)
+	for _, taskID := range []string{uploading, running, queued, queued2, queued3} {
+		waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+	}
+}
+
+// failingResultStore fails to store archives.
+type failingResultStore struct {
+	services.ResultStore
+}
+
+func (s failingResultStore) Put(context.Context, string, string) error {
+	return errors.New("disk is full")
+}
+
+func TestArchiveFailureReportedOnLinks(t *testing.T) {
+	payload := newPayloadServer(1024)
+	defer payload.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+		payload.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	local, err := localfs.NewLocal(cfg.ArchivesDir)
+	require.NoError(t, err)
+	service := newStoredTaskService(t, cfg, failingResultStore{ResultStore: local})
+
+	taskID := startTask(t, service, server.URL+"/a.pdf", server.URL+"/missing.pdf")
+	waitTaskStatus(t, service, taskID, models.FailedTaskStatus)
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+
+	archived := task.FilesLink[0]
+	require.Equal(t, models.ErrorTaskLinkStatus, archived.Status)
+	require.Equal(t, models.InternalFailureReason, archived.FailureReason)
+	require.Contains(t, archived.Error, "disk is full")
+	require.Equal(t, http.StatusOK, archived.HTTPStatus)
+	require.Equal(t, int64(1024), archived.BytesReceived)
+	require.Empty(t, archived.ArchiveName)
+
+	// Links that failed to download keep their own reason.
+	require.Equal(t, models.ErrorTaskLinkStatus, task.FilesLink[1].Status)
+	require.Equal(t, models.HTTPStatusFailureReason, task.FilesLink[1].FailureReason)
+	require.Equal(t, http.StatusNotFound, task.FilesLink[1].HTTPStatus)
+}
+
+// blockingResultStore blocks the first archive upload until its context is done, like an upload
+// cut off by the shutdown deadline.
+type blockingResultStore struct {
+	services.ResultStore
+	uploading chan struct{}
+	blocked   atomic.Bool
+}
+
+func (s *blockingResultStore) Put(ctx context.Context, name string, path string) error {
+	if s.blocked.CompareAndSwap(false, true) {
+		s.uploading <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return s.ResultStore.Put(ctx, name, path)
+}
+
+func TestDeleteTask(t *testing.T) {
+	ctx := context.Background()
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+
+	require.NoError(t, service.DeleteTask(ctx, taskID))
+	require.ErrorIs(t, service.DeleteTask(ctx, taskID), services.ErrTaskNotFound)
+}
+
+func TestArchiveFileNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download.pdf" {
+			w.Header().Set("Content-Disposition", `attachment; filename*=UTF-8''%D0%BE%D1%82%D1%87%D0%B5%D1%82.pdf`)
+		}
+		if r.URL.Path == "/hidden.pdf" {
+			w.Header().Set("Content-Disposition", `attachment; filename="../../.bashrc"`)
+		}
+		if r.URL.Path == "/invoice.pdf" {
+			w.Header().Set("Content-Disposition", `attachment; filename="invoice.exe"`)
+		}
+		if r.URL.Path == "/upper.pdf" {
+			w.Header().Set("Content-Disposition", `attachment; filename="UPPER.PDF"`)
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader+r.URL.Path)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.LinksInTask = 9
+	cfg.AutoStartTask = false
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf", Name: "../a.pdf"}})
+	require.ErrorIs(t, err, services.ErrValidation)
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{
+		{Link: server.URL + "/docs/a.pdf"},
+		{Link: server.URL + "/other/a.pdf"},
+		{Link: server.URL + "/A.pdf"},
+		{Link: server.URL + "/download.pdf"},
+		{Link: server.URL + "/hidden.pdf"},
+		{Link: server.URL + "/%3Cbad%3E.pdf"},
+		{Link: server.URL + "/x.pdf", Name: "a_1.pdf"},
+		{Link: server.URL + "/invoice.pdf"},
+		{Link: server.URL + "/upper.pdf"},
+	})
+	require.NoError(t, err)
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+
+	names := []string{"a.pdf", "a_1.pdf", "A_2.pdf", "отчет.pdf", "bashrc.pdf", "_bad_.pdf", "a_1_1.pdf", "invoice.exe.pdf", "UPPER.PDF"}
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	for idx, link := range task.FilesLink {
+		require.Equal(t, names[idx], link.ArchiveName, link.Link)
+	}
+
+	archive, err := zip.OpenReader(filepath.Join(cfg.ArchivesDir, taskID))
+	require.NoError(t, err)
+	defer archive.Close()
+
+	archived := make([]string, 0, len(archive.File))
+	for _, file := range archive.File {
+		archived = append(archived, file.Name)
+	}
+	require.Equal(t, names, archived)
+}
+
+func TestTaskReportsSizeLimit(t *testing.T) {
+	small := newPayloadServer(512)
+	defer small.Close()
+	big := newPayloadServer(2048)
+	defer big.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.MaxFileSize = 1024
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID := runTask(t, service, small.URL+"/a.pdf", big.URL+"/b.pdf")
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+
+	require.Equal(t, models.CompletedTaskLinkStatus, task.FilesLink[0].Status)
+	require.Zero(t, task.FilesLink[0].SizeLimit)
+	require.Equal(t, models.ErrorTaskLinkStatus, task.FilesLink[1].Status)
+	require.Equal(t, models.SizeLimitFailureReason, task.FilesLink[1].FailureReason)
+	require.Equal(t, int64(1024), task.FilesLink[1].SizeLimit)
+}
+
+func TestAddLinksChecksLinkPolicy(t *testing.T) {
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.AutoStartTask = false
+	cfg.LinksInTask = 10
+	cfg.AllowedSchemes = []string{"HTTPS"}
+	cfg.AllowedHosts = []string{"*.example.com", "files.example.org"}
+	cfg.DeniedHosts = []string{"private.example.com"}
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+
+	for _, link := range []string{
+		"not-a-url.pdf",
+		"http://cdn.example.com/a.pdf",
+		"ftp://cdn.example.com/a.pdf",
+		"https://example.com/a.pdf",
+		"https://PRIVATE.example.com./a.pdf",
+		"https://example.org/a.pdf",
+		"https://files.example.org.evil.com/a.pdf",
+	} {
+		_, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: link}})
+		require.ErrorIs(t, err, services.ErrValidation, link)
+	}
+
+	task, err := service.AddLinksToTask(ctx, taskID, []*models.FileLink{
+		{Link: "https://cdn.example.com/a.pdf"},
+		{Link: "https://a.cdn.example.com/b.pdf"},
+		{Link: "https://FILES.example.org/c.pdf"},
+	})
+	require.NoError(t, err)
+	require.Len(t, task.FilesLink, 3)
+}
+
+func TestAllowedExtensionsNormalized(t *testing.T) {
+	server := newPayloadServer(1024)
+	defer server.Close()
+
+	ctx := context.Background()
+	cfg := newServiceConfig(t)
+	cfg.AllowedExtensions = []string{"PDF", ".Png"}
+	service := newTaskService(t, cfg, inmemory.NewMemory())
+
+	taskID, err := service.NewTask(ctx, "")
+	require.NoError(t, err)
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.jpg"}})
+	require.ErrorIs(t, err, services.ErrValidation)
+
+	_, err = service.AddLinksToTask(ctx, taskID, []*models.FileLink{{Link: server.URL + "/a.pdf"}, {Link: server.URL + "/b.PNG"}})
+	require.NoError(t, err)
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+
+	task, err := service.GetTask(ctx, taskID)
+	require.NoError(t, err)
+	require.Equal(t, models.CompletedTaskLinkStatus, task.FilesLink[0].Status)
+}
+
+func TestTaskRecordsRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old.pdf" {
+			http.Redirect(w, r, "/new.pdf", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, pdfHeader)
+	}))
+	defer server.Close()
+
+	service := newTaskService(t, newServiceConfig(t), inmemory.NewMemory())
+	taskID := runTask(t, service, server.URL+"/old.pdf", server.URL+"/new.pdf")
+
+	task, err := service.GetTask(context.Background(), taskID)
+	require.NoError(t, err)
+	require.Equal(t, server.URL+"/new.pdf", task.FilesLink[0].FinalURL)
+	require.Equal(t, []string{server.URL + "/new.pdf"}, task.FilesLink[0].Redirects)
+	require.Equal(t, server.URL+"/new.pdf", task.FilesLink[1].FinalURL)
+	require.Empty(t, task.FilesLink[1].Redirects)
+}
+
+// runTask creates a task with the given links, starts it and waits until it is archived.
+func runTask(t *testing.T, service *services.TaskService, links ...string) string {
+	t.Helper()
+
+	return runFormatTask(t, service, "", links...)
+}
+
+// runFormatTask runs a task whose archive is written in the format.
+func runFormatTask(t *testing.T, service *services.TaskService, format models.ArchiveFormat, links ...string) string {
+	t.Helper()
+
+	taskID := startFormatTask(t, service, format, links...)
+	waitTaskStatus(t, service, taskID, models.ArchivedTaskStatus)
+
+	return taskID
+}
+
+// startTask creates a task with the given links and starts it.
+func startTask(t *testing.T, service *services.TaskService, links ...string) string {
+	t.Helper()
+
+	return startFormatTask(t, service, "", links...)
+}
+
+func startFormatTask(t *testing.T, service *services.TaskService, format models.ArchiveFormat, links ...string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	taskID, err := service.NewTask(ctx, format)
+	require.NoError(t, err)
+
+	fileLinks := make([]*models.FileLink, 0, len(links))
+	for _, link := range links {
+		fileLinks = append(fileLinks, &models.FileLink{Link: link})
+	}
+	_, err = service.AddLinksToTask(ctx, taskID, fileLinks)
+	require.NoError(t, err)
+
+	_, err = service.StartTask(ctx, taskID)
+	require.NoError(t, err)
+
+	return taskID
+}
+
+func newServiceConfig(t *testing.T) config.Config {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	cfg := newTestConfig()
+	cfg.ArchivesDir = "archives"
+	cfg.DownloadAttempts = 1
+
+	return cfg
+}
+
+func newTaskService(t *testing.T, cfg config.Config, repo services.TaskRepository) *services.TaskService {
+	t.Helper()
+
+	return newQueuedTaskService(t, cfg, repo, inmemory.NewQueue())
+}
+
+func newQueuedTaskService(
+	t *testing.T,
+	cfg config.Config,
+	repo services.TaskRepository,
+	queue services.TaskQueue,
+) *services.TaskService {
+	t.Helper()
+
+	results, err := localfs.NewLocal(cfg.ArchivesDir)
+	require.NoError(t, err)
+
+	return startTaskService(t, cfg, repo, queue, results)
+}
+
+func newStoredTaskService(t *testing.T, cfg config.Config, results services.ResultStore) *services.TaskService {
+	t.Helper()
+
+	return startTaskService(t, cfg, inmemory.NewMemory(), inmemory.NewQueue(), results)
+}
+
+func startTaskService(
+	t *testing.T,
+	cfg config.Config,
+	repo services.TaskRepository,
+	queue services.TaskQueue,
+	results services.ResultStore,
+) *services.TaskService {
+	t.Helper()
+
+	service := services.NewTaskService(
+		cfg,
+		slog.New(slog.DiscardHandler),
+		repo,
+		queue,
+		newRequester(t, cfg),
+		services.NewArchivers(cfg),
+		results,
+	)
+	require.NoError(t, service.Start(context.Background()))
+	t.Cleanup(service.Stop)
+
+	return service
+}
+
+func waitTaskStatus(t *testing.T, service *services.TaskService, taskID string, status models.TaskStatus) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		task, err := service.GetTask(context.Background(), taskID)
+		require.NoError(t, err)
+
+		return task.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+}